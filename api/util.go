@@ -29,6 +29,12 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// StatusError is an error with its own http status code
+type StatusError interface {
+	error
+	HTTPStatus() int
+}
+
 // GetBool parses http bool params
 func GetBool(ctx context.Context, r *http.Request, paramName string, defaultValue bool) (bool, error) {
 	boolValue := r.URL.Query().Get(paramName)
@@ -60,6 +66,70 @@ func GetInt(ctx context.Context, ps httprouter.Params, paramName string, default
 	return defaultValue, nil
 }
 
+// GetQueryInt parses http int params
+func GetQueryInt(ctx context.Context, r *http.Request, paramName string, defaultValue int) (int, error) {
+	valueStr := r.URL.Query().Get(paramName)
+	if len(valueStr) != 0 {
+		value, err := strconv.ParseInt(valueStr, 10, 32)
+		if err != nil {
+			msg := fmt.Sprintf("failed to parse '%v' value of the param '%s', expected an integer", valueStr, paramName)
+			log.Error(ctx, msg, err.Error())
+			return defaultValue, errors.New(msg)
+		}
+		return int(value), nil
+	}
+	return defaultValue, nil
+}
+
+// Page is a window into a list of ids
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+// GetPage parses the 'offset' and 'limit' http params
+func GetPage(ctx context.Context, r *http.Request, defaultLimit int, maxLimit int) (Page, error) {
+	return getPage(ctx, r, "offset", defaultLimit, maxLimit)
+}
+
+// GetCursorPage parses the 'cursor' and 'limit' http params
+// The cursor is the next offset of a previous page.
+func GetCursorPage(ctx context.Context, r *http.Request, defaultLimit int, maxLimit int) (Page, error) {
+	return getPage(ctx, r, "cursor", defaultLimit, maxLimit)
+}
+
+func getPage(ctx context.Context, r *http.Request, offsetName string, defaultLimit int, maxLimit int) (Page, error) {
+	offset, err := GetQueryInt(ctx, r, offsetName, 0)
+	if err != nil {
+		return Page{}, err
+	}
+	if offset < 0 {
+		return Page{}, fmt.Errorf("invalid '%s' param %d, expected a non-negative integer", offsetName, offset)
+	}
+
+	limit, err := GetQueryInt(ctx, r, "limit", defaultLimit)
+	if err != nil {
+		return Page{}, err
+	}
+	if limit <= 0 || limit > maxLimit {
+		return Page{}, fmt.Errorf("invalid 'limit' param %d, expected a value between 1 and %d", limit, maxLimit)
+	}
+
+	return Page{Offset: offset, Limit: limit}, nil
+}
+
+// Slice returns the ids within the page, and the offset of the next page or 0 if there are none
+func (p Page) Slice(ids []int) ([]int, int) {
+	if p.Offset >= len(ids) {
+		return []int{}, 0
+	}
+	end := p.Offset + p.Limit
+	if end >= len(ids) {
+		return ids[p.Offset:], 0
+	}
+	return ids[p.Offset:end], end
+}
+
 // GetSlice parses http slices params
 func GetSlice(ctx context.Context, r *http.Request, paramName string, defaultValue []int) ([]int, error) {
 	value := r.URL.Query().Get(paramName)
@@ -82,7 +152,13 @@ func GetSlice(ctx context.Context, r *http.Request, paramName string, defaultVal
 }
 
 // SerializeErr writes exceptional JSON responses
+// Responds 400 unless err is a StatusError
 func SerializeErr(ctx context.Context, w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if statusErr, ok := err.(StatusError); ok {
+		status = statusErr.HTTPStatus()
+	}
+
 	response := Response{Status: "error", Message: err.Error()}
 	b, err := marshal(response, true)
 	if err != nil {
@@ -90,7 +166,7 @@ func SerializeErr(ctx context.Context, w http.ResponseWriter, err error) {
 		http.Error(w, serverErrorJSON, 500)
 		return
 	}
-	http.Error(w, string(b), 400)
+	http.Error(w, string(b), status)
 }
 
 // SerializeData writes data as JSON
@@ -0,0 +1,4 @@
+// Package cachepb holds the protobuf messages of cache entries, generated from cache.proto
+package cachepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative cache.proto
@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cevaris/hnapi/model"
+)
+
+// ItemCallGroup tracks in-flight item hydrations, shared across requests
+type ItemCallGroup struct {
+	mu    sync.Mutex
+	calls map[int]*itemCall
+}
+
+type itemCall struct {
+	done chan struct{}
+	item model.Item
+	err  error
+}
+
+// NewItemCallGroup constructs an empty call group
+func NewItemCallGroup() *ItemCallGroup {
+	return &ItemCallGroup{calls: make(map[int]*itemCall)}
+}
+
+// CoalescingItemBackend deduplicates concurrent hydrations of the same item
+// Only the first caller for an item fetches it, later callers wait on its result.
+type CoalescingItemBackend struct {
+	itemBackend ItemBackend
+	group       *ItemCallGroup
+}
+
+// NewCoalescingItemBackend wraps itemBackend, coalescing calls through group
+func NewCoalescingItemBackend(itemBackend ItemBackend, group *ItemCallGroup) ItemBackend {
+	return &CoalescingItemBackend{
+		itemBackend: itemBackend,
+		group:       group,
+	}
+}
+
+// HydrateItem sends exactly one item or error per item id
+func (c *CoalescingItemBackend) HydrateItem(ctx context.Context, itemIds []int) (chan model.Item, chan error) {
+	itemChan := make(chan model.Item, len(itemIds))
+	errChan := make(chan error, len(itemIds))
+
+	for _, itemID := range itemIds {
+		go c.await(ctx, itemID, itemChan, errChan)
+	}
+	return itemChan, errChan
+}
+
+// lead fetches the item on behalf of every caller waiting on call
+func (c *CoalescingItemBackend) lead(ctx context.Context, itemID int, call *itemCall) {
+	innerItemChan, innerErrChan := c.itemBackend.HydrateItem(ctx, []int{itemID})
+	defer close(innerItemChan)
+	defer close(innerErrChan)
+
+	select {
+	case call.item = <-innerItemChan:
+	case call.err = <-innerErrChan:
+	}
+	c.group.finish(itemID, call)
+}
+
+// await joins or leads the hydration of itemID and sends its result
+func (c *CoalescingItemBackend) await(ctx context.Context, itemID int, itemChan chan<- model.Item, errChan chan<- error) {
+	for {
+		call, isLeader := c.group.join(itemID)
+		if isLeader {
+			log.Debug(ctx, itemID, "leading hydration")
+			go c.lead(ctx, itemID, call)
+		} else {
+			log.Debug(ctx, itemID, "joined in-flight hydration")
+		}
+
+		select {
+		case <-call.done:
+			// the leader's request went away, not ours, so try again
+			if isContextErr(call.err) && ctx.Err() == nil {
+				continue
+			}
+			if call.err != nil {
+				errChan <- call.err
+			} else {
+				itemChan <- call.item
+			}
+			return
+		case <-ctx.Done():
+			errChan <- &ItemError{ItemID: itemID, Err: ctx.Err()}
+			return
+		}
+	}
+}
+
+func (g *ItemCallGroup) join(itemID int) (*itemCall, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if call, ok := g.calls[itemID]; ok {
+		return call, false
+	}
+	call := &itemCall{done: make(chan struct{})}
+	g.calls[itemID] = call
+	return call, true
+}
+
+func (g *ItemCallGroup) finish(itemID int, call *itemCall) {
+	g.mu.Lock()
+	delete(g.calls, itemID)
+	g.mu.Unlock()
+	close(call.done)
+}
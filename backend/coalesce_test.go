@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cevaris/hnapi/model"
+)
+
+type countingItemBackend struct {
+	calls int32
+	delay time.Duration
+}
+
+func (b *countingItemBackend) HydrateItem(ctx context.Context, itemIds []int) (chan model.Item, chan error) {
+	itemChan := make(chan model.Item, len(itemIds))
+	errChan := make(chan error, len(itemIds))
+	for _, itemID := range itemIds {
+		atomic.AddInt32(&b.calls, 1)
+		time.Sleep(b.delay)
+		itemChan <- model.Item{ID: itemID}
+	}
+	return itemChan, errChan
+}
+
+func TestCoalescingItemBackendDeduplicatesInFlightCalls(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingItemBackend{delay: 50 * time.Millisecond}
+	group := NewItemCallGroup()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			itemBackend := NewCoalescingItemBackend(inner, group)
+			itemChan, errChan := itemBackend.HydrateItem(ctx, []int{1, 2})
+			for i := 0; i < 2; i++ {
+				select {
+				case <-itemChan:
+				case err := <-errChan:
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&inner.calls); calls != 2 {
+		t.Errorf("expected 2 upstream calls, got: %d", calls)
+	}
+}
+
+func TestCoalescingItemBackendHonorsCallerContext(t *testing.T) {
+	inner := &countingItemBackend{delay: 100 * time.Millisecond}
+	group := NewItemCallGroup()
+
+	go NewCoalescingItemBackend(inner, group).HydrateItem(context.Background(), []int{1})
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, errChan := NewCoalescingItemBackend(inner, group).HydrateItem(ctx, []int{1})
+
+	if err := <-errChan; cause(err) != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package backend
+
+import (
+	"time"
+
+	"github.com/cevaris/hnapi/backend/cachepb"
+	"github.com/cevaris/hnapi/model"
+	"github.com/golang/protobuf/proto"
+)
+
+// Cache entries implement clients.ProtoMarshaler for the protobuf codec,
+// converting to the messages of cachepb/cache.proto
+
+// cachedUser is the cache entry of a user
+type cachedUser model.User
+
+// cachedFeed is the cache entry of the item ids of a feed
+type cachedFeed []int
+
+// ToProto implements clients.ProtoMarshaler
+func (c *cachedItem) ToProto() proto.Message {
+	i := c.Item
+	return &cachepb.CachedItem{
+		Item: &cachepb.Item{
+			Id:          int64(i.ID),
+			Type:        i.Type,
+			By:          i.By,
+			Time:        int64(i.Time),
+			Deleted:     i.Deleted,
+			Dead:        i.Dead,
+			Parent:      int64(i.Parent),
+			Text:        i.Text,
+			Poll:        int64(i.Poll),
+			Parts:       toInt64s(i.Parts),
+			Descendants: int64(i.Decendants),
+			Kids:        toInt64s(i.Kids),
+			Url:         i.URL,
+			Score:       int64(i.Score),
+			Title:       i.Title,
+			Missing:     i.Missing,
+		},
+		SoftExpiryUnixNano: c.SoftExpiry.UnixNano(),
+	}
+}
+
+// FromProto implements clients.ProtoMarshaler
+func (c *cachedItem) FromProto(m proto.Message) {
+	entry := m.(*cachepb.CachedItem)
+	c.SoftExpiry = time.Unix(0, entry.SoftExpiryUnixNano)
+	c.Item = model.Item{}
+	if i := entry.Item; i != nil {
+		c.Item = model.Item{
+			ID:         int(i.Id),
+			Type:       i.Type,
+			By:         i.By,
+			Time:       int(i.Time),
+			Deleted:    i.Deleted,
+			Dead:       i.Dead,
+			Parent:     int(i.Parent),
+			Text:       i.Text,
+			Poll:       int(i.Poll),
+			Parts:      toInts(i.Parts),
+			Decendants: int(i.Descendants),
+			Kids:       toInts(i.Kids),
+			URL:        i.Url,
+			Score:      int(i.Score),
+			Title:      i.Title,
+			Missing:    i.Missing,
+		}
+	}
+}
+
+// ToProto implements clients.ProtoMarshaler
+func (u *cachedUser) ToProto() proto.Message {
+	return &cachepb.User{
+		Id:        u.ID,
+		Created:   int64(u.Created),
+		Karma:     int64(u.Karma),
+		About:     u.About,
+		Submitted: toInt64s(u.Submitted),
+	}
+}
+
+// FromProto implements clients.ProtoMarshaler
+func (u *cachedUser) FromProto(m proto.Message) {
+	user := m.(*cachepb.User)
+	*u = cachedUser{
+		ID:        user.Id,
+		Created:   int(user.Created),
+		Karma:     int(user.Karma),
+		About:     user.About,
+		Submitted: toInts(user.Submitted),
+	}
+}
+
+// ToProto implements clients.ProtoMarshaler
+func (f *cachedFeed) ToProto() proto.Message {
+	return &cachepb.Feed{ItemIds: toInt64s(*f)}
+}
+
+// FromProto implements clients.ProtoMarshaler
+func (f *cachedFeed) FromProto(m proto.Message) {
+	*f = toInts(m.(*cachepb.Feed).ItemIds)
+}
+
+func toInt64s(ints []int) []int64 {
+	if len(ints) == 0 {
+		return nil
+	}
+	result := make([]int64, len(ints))
+	for i, v := range ints {
+		result[i] = int64(v)
+	}
+	return result
+}
+
+func toInts(ints []int64) []int {
+	if len(ints) == 0 {
+		return nil
+	}
+	result := make([]int, len(ints))
+	for i, v := range ints {
+		result[i] = int(v)
+	}
+	return result
+}
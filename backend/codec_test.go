@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cevaris/hnapi/clients"
+	"github.com/cevaris/hnapi/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCacheEntriesRoundTrip(t *testing.T) {
+	item := cachedItem{
+		Item: model.Item{
+			ID: 8863, Type: "story", By: "dhouston", Time: 1175714200, Kids: []int{8952, 9224},
+			Decendants: 71, Score: 111, Title: "My YC app: Dropbox", URL: "http://www.getdropbox.com/u/2/screencast.html",
+		},
+		SoftExpiry: time.Unix(0, 1175714200123456789),
+	}
+	user := cachedUser{ID: "pg", Created: 1160418092, Karma: 157236, Submitted: []int{8, 3, 1}}
+	feed := cachedFeed{1, 8, 7}
+
+	for _, name := range []string{"json", "proto", "msgpack"} {
+		codec, err := clients.NewCodec(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var actualItem cachedItem
+		b, err := clients.ToBytes(codec, &item)
+		if err == nil {
+			err = clients.FromBytes(codec, b, &actualItem)
+		}
+		if err != nil || !cmp.Equal(item.Item, actualItem.Item) || !item.SoftExpiry.Equal(actualItem.SoftExpiry) {
+			t.Errorf("%s item round trip failed, got: %v %v", name, actualItem, err)
+		}
+
+		var actualUser cachedUser
+		b, err = clients.ToBytes(codec, &user)
+		if err == nil {
+			err = clients.FromBytes(codec, b, &actualUser)
+		}
+		if err != nil || !cmp.Equal(user, actualUser) {
+			t.Errorf("%s user round trip failed, got: %v %v", name, actualUser, err)
+		}
+
+		var actualFeed cachedFeed
+		b, err = clients.ToBytes(codec, &feed)
+		if err == nil {
+			err = clients.FromBytes(codec, b, &actualFeed)
+		}
+		if err != nil || !cmp.Equal(feed, actualFeed) {
+			t.Errorf("%s feed round trip failed, got: %v %v", name, actualFeed, err)
+		}
+	}
+}
+
+func TestProtoCacheEntriesIgnoreUnknownFields(t *testing.T) {
+	codec := clients.NewProtoCodec()
+	b, _ := clients.ToBytes(codec, &cachedFeed{1, 2})
+	// append field 15 as a string, written by a newer schema
+	b = append(b, 15<<3|2, 3, 'n', 'e', 'w')
+
+	var actual cachedFeed
+	if err := clients.FromBytes(codec, b, &actual); err != nil || !cmp.Equal(cachedFeed{1, 2}, actual) {
+		t.Errorf("expected unknown fields to be skipped, got: %v %v", actual, err)
+	}
+}
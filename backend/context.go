@@ -0,0 +1,21 @@
+package backend
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext keeps the values of its parent but none of its deadline or cancellation
+// Lets background work outlive a request while still carrying its app engine context.
+type detachedContext struct {
+	parent context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (d detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detachedContext) Done() <-chan struct{}             { return nil }
+func (d detachedContext) Err() error                        { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cevaris/hnapi/clients"
+	"github.com/cevaris/hnapi/model"
+)
+
+// ErrNotFound the HN API has no such resource, it responded with 404 or null
+type ErrNotFound struct {
+	Resource string
+	ItemID   int
+	Status   int
+}
+
+func (e *ErrNotFound) Error() string {
+	return fmt.Sprintf("%s not found", e.Resource)
+}
+
+// HTTPStatus maps to 404
+func (e *ErrNotFound) HTTPStatus() int {
+	return http.StatusNotFound
+}
+
+// ErrUpstreamStatus the HN API responded with an unexpected status
+type ErrUpstreamStatus struct {
+	Resource string
+	ItemID   int
+	Status   int
+}
+
+func (e *ErrUpstreamStatus) Error() string {
+	return fmt.Sprintf("upstream responded %d for %s", e.Status, e.Resource)
+}
+
+// HTTPStatus maps to 502
+func (e *ErrUpstreamStatus) HTTPStatus() int {
+	return http.StatusBadGateway
+}
+
+// ErrDecode the HN API response was not the expected json
+type ErrDecode struct {
+	Resource    string
+	ItemID      int
+	Status      int
+	ContentType string
+	Err         error
+}
+
+func (e *ErrDecode) Error() string {
+	return fmt.Sprintf("failed decoding %s: %v", e.Resource, e.Err)
+}
+
+// HTTPStatus maps to 502
+func (e *ErrDecode) HTTPStatus() int {
+	return http.StatusBadGateway
+}
+
+// ErrUpstream the HN API could not be reached or its response could not be read
+type ErrUpstream struct {
+	Resource string
+	ItemID   int
+	Err      error
+}
+
+func (e *ErrUpstream) Error() string {
+	return fmt.Sprintf("failed fetching %s: %v", e.Resource, e.Err)
+}
+
+// HTTPStatus maps timeouts to 504 and an open circuit to 503
+func (e *ErrUpstream) HTTPStatus() int {
+	return upstreamStatus(e)
+}
+
+// ItemError is a failure hydrating a specific item, before any response was received
+type ItemError struct {
+	ItemID int
+	Err    error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.ItemID, e.Err)
+}
+
+// HTTPStatus maps timeouts to 504 and an open circuit to 503
+func (e *ItemError) HTTPStatus() int {
+	return upstreamStatus(e)
+}
+
+// upstreamStatus maps an error reaching the HN API to a status
+func upstreamStatus(err error) int {
+	switch cause(err) {
+	case context.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case clients.ErrCircuitOpen:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
+
+// PartialError reports the items that failed, the rest were hydrated
+type PartialError struct {
+	Failures []model.Failure
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("failed to hydrate %d items", len(e.Failures))
+}
+
+// HTTPStatus maps to 502
+func (e *PartialError) HTTPStatus() int {
+	return http.StatusBadGateway
+}
+
+// errItemID returns the id of the item an error is about
+func errItemID(err error) (int, bool) {
+	switch e := err.(type) {
+	case *ItemError:
+		return e.ItemID, true
+	case *ErrNotFound:
+		return e.ItemID, e.ItemID != 0
+	case *ErrUpstreamStatus:
+		return e.ItemID, e.ItemID != 0
+	case *ErrDecode:
+		return e.ItemID, e.ItemID != 0
+	case *ErrUpstream:
+		return e.ItemID, e.ItemID != 0
+	}
+	return 0, false
+}
+
+// cause unwraps ItemErrors, ErrUpstreams and url.Errors down to the underlying error
+func cause(err error) error {
+	for {
+		switch e := err.(type) {
+		case *ItemError:
+			err = e.Err
+		case *ErrUpstream:
+			err = e.Err
+		case *url.Error:
+			err = e.Err
+		default:
+			return err
+		}
+	}
+}
+
+func isContextErr(err error) bool {
+	err = cause(err)
+	return err == context.Canceled || err == context.DeadlineExceeded
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(*ErrNotFound)
+	return ok
+}
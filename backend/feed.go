@@ -0,0 +1,105 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cevaris/hnapi/clients"
+)
+
+var feedCacheDurationTTL = time.Minute * time.Duration(1)
+
+// Feeds maps supported feed names to their HN API endpoint
+var Feeds = map[string]string{
+	"top":  "topstories",
+	"new":  "newstories",
+	"best": "beststories",
+	"ask":  "askstories",
+	"show": "showstories",
+	"job":  "jobstories",
+}
+
+// FeedBackend hydrates the item ids of a feed
+type FeedBackend interface {
+	HydrateFeed(ctx context.Context, feed string) ([]int, error)
+}
+
+// FireBaseFeedBackend firebase backed feed client
+type FireBaseFeedBackend struct {
+	client   clients.HTTPClient
+	baseURLs []string
+}
+
+// NewFireBaseFeedBackend constructs a new feed backend
+// Feeds are fetched from the first of baseURLs that responds, defaulting to DefaultBaseURL.
+func NewFireBaseFeedBackend(httpClient clients.HTTPClient, baseURLs ...string) FeedBackend {
+	return &FireBaseFeedBackend{client: httpClient, baseURLs: orDefaultBaseURLs(baseURLs)}
+}
+
+// HydrateFeed fetches the ordered item ids of a feed
+func (f *FireBaseFeedBackend) HydrateFeed(ctx context.Context, feed string) ([]int, error) {
+	endpoint, ok := Feeds[feed]
+	if !ok {
+		return nil, fmt.Errorf("unknown feed '%s'", feed)
+	}
+
+	path := fmt.Sprintf("/%s.json", endpoint)
+
+	itemIds := make([]int, 0)
+	err := getJSON(ctx, f.client, f.baseURLs, path, fmt.Sprintf("feed %s", feed), 0, &itemIds)
+	if err != nil {
+		log.Error(ctx, "failed to hydrate feed", feed, err)
+		return nil, err
+	}
+	return itemIds, nil
+}
+
+// FeedRepo hydrates feed item ids
+type FeedRepo interface {
+	Get(context.Context, string) ([]int, error)
+}
+
+// CachedFeedRepo hydrates and caches feed item ids
+type CachedFeedRepo struct {
+	feedBackend  FeedBackend
+	cacheBackend clients.CacheClient
+}
+
+// NewCachedFeedRepo cached backed feed repository
+func NewCachedFeedRepo(feedBackend FeedBackend, cacheBackend clients.CacheClient) FeedRepo {
+	return &CachedFeedRepo{
+		feedBackend:  feedBackend,
+		cacheBackend: cacheBackend,
+	}
+}
+
+// Get cached feed item ids
+func (c *CachedFeedRepo) Get(ctx context.Context, feed string) ([]int, error) {
+	key := feedCacheKey(feed)
+
+	itemIds := make([]int, 0)
+	err := c.cacheBackend.Get(ctx, key, (*cachedFeed)(&itemIds))
+	if err == nil {
+		log.Info(ctx, "cache hit", key)
+		return itemIds, nil
+	}
+
+	itemIds, err = c.feedBackend.HydrateFeed(ctx, feed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.cacheBackend.Set(ctx, key, (*cachedFeed)(&itemIds), feedCacheDurationTTL)
+	if err != nil {
+		log.Error(ctx, "failed to write to cache", key, err)
+	} else {
+		log.Debug(ctx, "wrote to cache", key)
+	}
+
+	return itemIds, nil
+}
+
+func feedCacheKey(feed string) string {
+	return fmt.Sprintf("feed:%s", feed)
+}
@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/cevaris/hnapi/clients"
+)
+
+// DefaultBaseURL of the HN API
+const DefaultBaseURL = "https://hacker-news.firebaseio.com/v0"
+
+// orDefaultBaseURLs falls back to DefaultBaseURL when no base urls are configured
+func orDefaultBaseURLs(baseURLs []string) []string {
+	if len(baseURLs) == 0 {
+		return []string{DefaultBaseURL}
+	}
+	return baseURLs
+}
+
+// getJSON fetches path from the first base url that responds, falling back through
+// the rest in order, and decodes its json body into result.
+// A not found response is final, there is no point asking mirrors.
+func getJSON(ctx context.Context, client clients.HTTPClient, baseURLs []string, path string, resource string, itemID int, result interface{}) error {
+	var err error
+	for _, baseURL := range baseURLs {
+		err = getJSONFrom(ctx, client, strings.TrimSuffix(baseURL, "/")+path, resource, itemID, result)
+		if err == nil || isNotFound(err) || ctx.Err() != nil {
+			return err
+		}
+		log.Info(ctx, "falling back from", baseURL, err)
+	}
+	return err
+}
+
+// getJSONFrom fetches url and decodes its json body into result
+// Responds with ErrNotFound, ErrUpstreamStatus or ErrDecode once the HN API responded,
+// and ErrUpstream when it could not be reached or read,
+// resource and itemID describe what was fetched for those errors.
+func getJSONFrom(ctx context.Context, client clients.HTTPClient, url string, resource string, itemID int, result interface{}) error {
+	resp, err := clients.Get(ctx, client, url)
+	if err != nil {
+		log.Error(ctx, "failed making http request", url, err)
+		// the client wraps the context error in a *url.Error
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return &ErrUpstream{Resource: resource, ItemID: itemID, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &ErrNotFound{Resource: resource, ItemID: itemID, Status: resp.StatusCode}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Error(ctx, "unexpected http status", url, resp.StatusCode)
+		return &ErrUpstreamStatus{Resource: resource, ItemID: itemID, Status: resp.StatusCode}
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" && !strings.Contains(contentType, "json") {
+		log.Error(ctx, "unexpected content type", url, contentType)
+		return &ErrDecode{Resource: resource, ItemID: itemID, Status: resp.StatusCode, ContentType: contentType, Err: fmt.Errorf("unexpected content type %s", contentType)}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Error(ctx, "failed reading http response", url, err)
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return &ErrUpstream{Resource: resource, ItemID: itemID, Err: err}
+	}
+
+	// firebase responds with null for unknown resources
+	if bytes.Equal(bytes.TrimSpace(body), []byte("null")) {
+		return &ErrNotFound{Resource: resource, ItemID: itemID, Status: resp.StatusCode}
+	}
+
+	err = json.Unmarshal(body, result)
+	if err != nil {
+		log.Error(ctx, "failed unmarshalling", resource, string(body), err)
+		return &ErrDecode{Resource: resource, ItemID: itemID, Status: resp.StatusCode, ContentType: contentType, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cevaris/hnapi/model"
+)
+
+type stubHTTPClient struct {
+	status      int
+	contentType string
+	body        string
+}
+
+func (c *stubHTTPClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: c.status,
+		Header:     http.Header{"Content-Type": []string{c.contentType}},
+		Body:       ioutil.NopCloser(strings.NewReader(c.body)),
+	}, nil
+}
+
+func TestGetJSONTypedErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		client *stubHTTPClient
+		check  func(error) bool
+	}{
+		{&stubHTTPClient{200, "application/json", `{"id": 1}`}, func(err error) bool { return err == nil }},
+		{&stubHTTPClient{200, "application/json", `null`}, func(err error) bool { _, ok := err.(*ErrNotFound); return ok }},
+		{&stubHTTPClient{404, "application/json", ``}, func(err error) bool { _, ok := err.(*ErrNotFound); return ok }},
+		{&stubHTTPClient{401, "text/html", `<html>`}, func(err error) bool { e, ok := err.(*ErrUpstreamStatus); return ok && e.Status == 401 }},
+		{&stubHTTPClient{200, "text/html", `<html>`}, func(err error) bool { _, ok := err.(*ErrDecode); return ok }},
+		{&stubHTTPClient{200, "application/json", `{"id": "x"}`}, func(err error) bool { _, ok := err.(*ErrDecode); return ok }},
+	}
+
+	for _, test := range tests {
+		var item model.Item
+		err := getJSON(ctx, test.client, []string{"http://hn/v0"}, "/item/1.json", "item 1", 1, &item)
+		if !test.check(err) {
+			t.Errorf("unexpected error for %v, got: %v", test.client, err)
+		}
+		if itemID, ok := errItemID(err); err != nil && (!ok || itemID != 1) {
+			t.Errorf("expected error about item 1, got: %v", err)
+		}
+	}
+}
+
+type hostsHTTPClient map[string]*stubHTTPClient
+
+func (c hostsHTTPClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return c[req.URL.Host].Do(ctx, req)
+}
+
+func TestGetJSONFallsBackThroughMirrors(t *testing.T) {
+	ctx := context.Background()
+	client := hostsHTTPClient{
+		"primary": &stubHTTPClient{503, "text/html", `<html>`},
+		"mirror":  &stubHTTPClient{200, "application/json", `{"id": 1}`},
+		"dump":    &stubHTTPClient{200, "application/json", `null`},
+	}
+
+	var item model.Item
+	err := getJSON(ctx, client, []string{"http://primary/v0", "http://mirror/v0/"}, "/item/1.json", "item 1", 1, &item)
+	if err != nil || item.ID != 1 {
+		t.Errorf("expected mirror to serve the item, got: %v, %v", item, err)
+	}
+
+	err = getJSON(ctx, client, []string{"http://dump/v0", "http://mirror/v0"}, "/item/1.json", "item 1", 1, &item)
+	if !isNotFound(err) {
+		t.Errorf("expected not found to be final, got: %v", err)
+	}
+}
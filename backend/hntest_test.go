@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cevaris/hnapi/clients"
+	"github.com/cevaris/hnapi/hntest"
+	"github.com/cevaris/hnapi/model"
+)
+
+func TestCachedItemRepoHydratesFromFakeHN(t *testing.T) {
+	ctx := context.Background()
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	fake.SetStatus(hntest.ItemPath(3), http.StatusInternalServerError)
+
+	itemBackend := NewFireBaseItemBackend(clients.NewGoPClient(), NewLimiter(DefaultMaxRequests, DefaultMaxBulkRequests), fake.BaseURL())
+	itemRepo := NewCachedItemRepo(itemBackend, clients.NewLRUCacheClient(10, clients.NewJSONCodec()))
+
+	for i := 0; i < 2; i++ {
+		items, err := itemRepo.Get(ctx, []int{1, 3, 99})
+		byID := make(map[int]model.Item)
+		for _, item := range items {
+			byID[item.ID] = item
+		}
+		if len(items) != 3 || byID[1].Title != "Y Combinator" {
+			t.Errorf("expected item 1 to be hydrated, got: %v", items)
+		}
+		if byID[3].Missing != model.MissingError {
+			t.Errorf("expected failed placeholder for item 3, got: %v", byID[3])
+		}
+		if byID[99].Missing != model.MissingNotFound {
+			t.Errorf("expected not found placeholder for item 99, got: %v", byID[99])
+		}
+		if partialErr, ok := err.(*PartialError); !ok || len(partialErr.Failures) != 2 {
+			t.Errorf("expected a partial error with 2 failures, got: %v", err)
+		}
+	}
+
+	for _, itemID := range []int{1, 3, 99} {
+		if requests := fake.Requests(hntest.ItemPath(itemID)); requests != 1 {
+			t.Errorf("expected item %d to be cached, got %d requests", itemID, requests)
+		}
+	}
+}
+
+func TestFireBaseUserAndFeedBackendsAgainstFakeHN(t *testing.T) {
+	ctx := context.Background()
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+
+	itemIds, err := NewFireBaseFeedBackend(clients.NewGoPClient(), fake.BaseURL()).HydrateFeed(ctx, "top")
+	if err != nil || len(itemIds) != 3 || itemIds[0] != 1 {
+		t.Errorf("expected top feed, got: %v %v", itemIds, err)
+	}
+
+	user, err := NewFireBaseUserBackend(clients.NewGoPClient(), fake.BaseURL()).HydrateUser(ctx, "pg")
+	if err != nil || user.ID != "pg" || len(user.Submitted) != 3 {
+		t.Errorf("expected user pg, got: %v %v", user, err)
+	}
+
+	fake.SetNull(hntest.UserPath("pg"))
+	_, err = NewFireBaseUserBackend(clients.NewGoPClient(), fake.BaseURL()).HydrateUser(ctx, "pg")
+	if !isNotFound(err) {
+		t.Errorf("expected null user to be not found, got: %v", err)
+	}
+}
+
+func TestCancelledLeaderDoesNotPoisonItem(t *testing.T) {
+	ctx := context.Background()
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	fake.SetLatency(300 * time.Millisecond)
+
+	firebase := NewFireBaseItemBackend(clients.NewGoPClient(), NewLimiter(DefaultMaxRequests, DefaultMaxBulkRequests), fake.BaseURL())
+	itemRepo := NewCachedItemRepo(NewCoalescingItemBackend(firebase, NewItemCallGroup()), clients.NewLRUCacheClient(10, clients.NewJSONCodec()))
+
+	leaderCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		items, err := itemRepo.Get(leaderCtx, []int{1})
+		if len(items) != 1 || items[0].Missing != model.MissingError {
+			t.Errorf("expected timed out leader to fail, got: %v", items)
+		}
+		partialErr, ok := err.(*PartialError)
+		if !ok || len(partialErr.Failures) != 1 || partialErr.Failures[0].Message != context.DeadlineExceeded.Error() {
+			t.Errorf("expected leader to fail with its deadline, got: %v", err)
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	items, err := itemRepo.Get(ctx, []int{1})
+	if err != nil || len(items) != 1 || items[0].Title != "Y Combinator" {
+		t.Errorf("expected joined caller to hydrate the item, got: %v %v", items, err)
+	}
+	wg.Wait()
+
+	requests := fake.Requests(hntest.ItemPath(1))
+	items, err = itemRepo.Get(ctx, []int{1})
+	if err != nil || len(items) != 1 || items[0].Missing != "" {
+		t.Errorf("expected the item to be cached, got: %v %v", items, err)
+	}
+	if fake.Requests(hntest.ItemPath(1)) != requests {
+		t.Errorf("expected the item to be served from cache")
+	}
+}
+
+func TestItemErrorMapsWrappedDeadlineToGatewayTimeout(t *testing.T) {
+	err := &ItemError{ItemID: 1, Err: &url.Error{Op: "Get", URL: "http://hn", Err: context.DeadlineExceeded}}
+	if status := err.HTTPStatus(); status != http.StatusGatewayTimeout {
+		t.Errorf("expected 504, got %d", status)
+	}
+	if !isContextErr(err) {
+		t.Errorf("expected wrapped deadline to be a context error")
+	}
+}
@@ -2,9 +2,7 @@ package backend
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"runtime"
 
 	"github.com/cevaris/hnapi/clients"
@@ -12,38 +10,24 @@ import (
 )
 
 // ItemBackend hydrates Items
+// Every item id yields exactly one item or error about it, one of
+// *ItemError, *ErrUpstream, *ErrNotFound, *ErrUpstreamStatus or *ErrDecode
 type ItemBackend interface {
 	HydrateItem(ctx context.Context, itemIds []int) (chan model.Item, chan error)
 }
 
 // FireBaseItemBackend firebase backed http client
 type FireBaseItemBackend struct {
-	// client *pester.Client
-	// client *http.Client
-	client clients.HTTPClient
+	client   clients.HTTPClient
+	limiter  Limiter
+	baseURLs []string
 }
 
 // NewFireBaseItemBackend constructs a new item repo
-func NewFireBaseItemBackend(httpClient clients.HTTPClient) ItemBackend {
-	// client := pester.New()
-	// client.Concurrency = 1
-	// client.MaxRetries = 5
-	// client.Backoff = pester.ExponentialBackoff
-	// var client = urlfetch.Client(ctx) // &http.Client{Timeout: 10 * time.Second}
-	return &FireBaseItemBackend{client: httpClient}
-}
-
-// MAX http requests
-// TODO move this outside of the backend, into a global helper method and pass into NewFireBaseItemBackend
-const MAX = 30
-
-var sem = make(chan int, MAX)
-
-func incr() {
-	sem <- 1
-}
-func decr() {
-	<-sem
+// limiter is usually shared by every backend of the process, retries are left to httpClient.
+// Items are fetched from the first of baseURLs that responds, defaulting to DefaultBaseURL.
+func NewFireBaseItemBackend(httpClient clients.HTTPClient, limiter Limiter, baseURLs ...string) ItemBackend {
+	return &FireBaseItemBackend{client: httpClient, limiter: limiter, baseURLs: orDefaultBaseURLs(baseURLs)}
 }
 
 // HydrateItem https://venilnoronha.io/designing-asynchronous-functions-with-go
@@ -52,48 +36,40 @@ func (f *FireBaseItemBackend) HydrateItem(ctx context.Context, itemIds []int) (c
 	itemChan := make(chan model.Item, len(itemIds))
 	errChan := make(chan error, len(itemIds))
 
-	for _, itemID := range itemIds {
-		log.Info(ctx, fmt.Sprintf("processing=%d goroutines=%d", len(sem), runtime.NumGoroutine()))
-		incr()
+	for i, itemID := range itemIds {
+		log.Info(ctx, fmt.Sprintf("hydrating=%d goroutines=%d", itemID, runtime.NumGoroutine()))
+		err := f.limiter.Acquire(ctx)
+		if err != nil {
+			// short circuit the remaining items
+			for _, itemID := range itemIds[i:] {
+				errChan <- &ItemError{ItemID: itemID, Err: err}
+			}
+			break
+		}
 		go f.asyncHydrate(ctx, itemID, itemChan, errChan)
 	}
 	return itemChan, errChan
 }
 
 func (f *FireBaseItemBackend) asyncHydrate(ctx context.Context, itemID int, itemChan chan<- model.Item, errChan chan<- error) {
-	defer decr()
+	defer f.limiter.Release(ctx)
 
 	select {
 	case <-ctx.Done():
-		errChan <- ctx.Err()
+		errChan <- &ItemError{ItemID: itemID, Err: ctx.Err()}
 		return // short circuit
 	default:
 	}
 
 	log.Debug(ctx, itemID, "fetching item")
-	url := fmt.Sprintf("https://hacker-news.firebaseio.com/v0/item/%d.json?print=pretty", itemID)
-	resp, err := f.client.Get(url)
-	if err != nil {
-		log.Error(ctx, "failed making http request", url)
-		errChan <- err
-		return
-	}
-	defer resp.Body.Close()
-	log.Debug(ctx, itemID, "fetched items")
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Error(ctx, itemID, "failed reading http response")
-		errChan <- err
-		return
-	}
-
-	log.Debug(ctx, itemID, "hydrated")
+	path := fmt.Sprintf("/item/%d.json", itemID)
 
 	var item model.Item
-	err = json.Unmarshal(body, &item)
+	err := getJSON(ctx, f.client, f.baseURLs, path, fmt.Sprintf("item %d", itemID), itemID, &item)
+	if _, ok := errItemID(err); err != nil && !ok {
+		err = &ItemError{ItemID: itemID, Err: err}
+	}
 	if err != nil {
-		log.Error(ctx, "failed unmarshalling item", string(body), err)
 		errChan <- err
 		return
 	}
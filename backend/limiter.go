@@ -0,0 +1,77 @@
+package backend
+
+import "context"
+
+// DefaultMaxRequests concurrent http requests per limiter
+const DefaultMaxRequests = 30
+
+// DefaultMaxBulkRequests concurrent http requests bulk hydrations may hold
+const DefaultMaxBulkRequests = 20
+
+type bulkKey struct{}
+
+// WithBulk marks hydrations made with ctx as bulk, such as whole feeds
+// Bulk hydrations only get a share of the limiter so they can't starve other lookups.
+func WithBulk(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bulkKey{}, true)
+}
+
+func isBulk(ctx context.Context) bool {
+	bulk, _ := ctx.Value(bulkKey{}).(bool)
+	return bulk
+}
+
+// Limiter bounds concurrent upstream requests
+type Limiter interface {
+	// Acquire blocks until a slot is free or ctx is done
+	Acquire(ctx context.Context) error
+	// Release frees the slot acquired with ctx
+	Release(ctx context.Context)
+}
+
+// semaphoreLimiter reserves max-maxBulk slots for non bulk hydrations
+type semaphoreLimiter struct {
+	slots     chan struct{}
+	bulkSlots chan struct{}
+}
+
+// NewLimiter constructs a limiter allowing max concurrent requests, at most maxBulk of them bulk
+func NewLimiter(max int, maxBulk int) Limiter {
+	if maxBulk > max {
+		maxBulk = max
+	}
+	return &semaphoreLimiter{
+		slots:     make(chan struct{}, max),
+		bulkSlots: make(chan struct{}, maxBulk),
+	}
+}
+
+// Acquire a slot
+func (l *semaphoreLimiter) Acquire(ctx context.Context) error {
+	bulk := isBulk(ctx)
+	if bulk {
+		select {
+		case l.bulkSlots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		if bulk {
+			<-l.bulkSlots
+		}
+		return ctx.Err()
+	}
+}
+
+// Release a slot
+func (l *semaphoreLimiter) Release(ctx context.Context) {
+	<-l.slots
+	if isBulk(ctx) {
+		<-l.bulkSlots
+	}
+}
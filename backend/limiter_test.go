@@ -0,0 +1,42 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterReservesSlotsForNonBulk(t *testing.T) {
+	limiter := NewLimiter(2, 1)
+	bulkCtx := WithBulk(context.Background())
+
+	if err := limiter.Acquire(bulkCtx); err != nil {
+		t.Fatalf("expected bulk slot, got: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(bulkCtx, 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected bulk share to be exhausted, got: %v", err)
+	}
+
+	if err := limiter.Acquire(context.Background()); err != nil {
+		t.Errorf("expected reserved slot, got: %v", err)
+	}
+
+	limiter.Release(bulkCtx)
+	if err := limiter.Acquire(bulkCtx); err != nil {
+		t.Errorf("expected released bulk slot, got: %v", err)
+	}
+}
+
+func TestLimiterAcquireHonorsContext(t *testing.T) {
+	limiter := NewLimiter(1, 1)
+	limiter.Acquire(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Acquire(ctx); err != context.Canceled {
+		t.Errorf("expected cancelled acquire, got: %v", err)
+	}
+}
@@ -11,7 +11,46 @@ import (
 
 var cacheDurationTTL = time.Minute * time.Duration(5)
 
+// negativeCacheTTL caches not found, deleted and dead items briefly
+var negativeCacheTTL = CacheTTL{Soft: time.Minute, Hard: time.Minute}
+
+// failedCacheTTL keeps failing items from hammering upstream
+var failedCacheTTL = CacheTTL{Soft: 10 * time.Second, Hard: 10 * time.Second}
+
+// revalidateTimeout bounds background refreshes of stale items
+var revalidateTimeout = time.Second * time.Duration(10)
+
+// CacheTTL is the soft and hard expiry of a cache entry
+// Past Soft the entry is served stale while refreshed in the background,
+// past Hard the entry is evicted and callers block on the backend.
+type CacheTTL struct {
+	Soft time.Duration
+	Hard time.Duration
+}
+
+// ItemCacheTTLs maps item types to their cache ttl, the "" entry is the default
+type ItemCacheTTLs map[string]CacheTTL
+
+// DefaultItemCacheTTLs stories change score quickly, comments and jobs rarely change
+var DefaultItemCacheTTLs = ItemCacheTTLs{
+	"":        {Soft: time.Minute, Hard: cacheDurationTTL},
+	"story":   {Soft: time.Minute, Hard: cacheDurationTTL},
+	"poll":    {Soft: time.Minute, Hard: cacheDurationTTL},
+	"comment": {Soft: 5 * time.Minute, Hard: 30 * time.Minute},
+	"job":     {Soft: 30 * time.Minute, Hard: 2 * time.Hour},
+}
+
+// ttl for the given item type, falling back to the default entry
+func (t ItemCacheTTLs) ttl(itemType string) CacheTTL {
+	if ttl, ok := t[itemType]; ok {
+		return ttl
+	}
+	return t[""]
+}
+
 // ItemRepo hydrate me
+// Returns one item per distinct id, in the order ids were first requested, with
+// Missing placeholders for items that could not be hydrated and a *PartialError describing them
 type ItemRepo interface {
 	Get(context.Context, []int) ([]model.Item, error)
 }
@@ -20,13 +59,26 @@ type ItemRepo interface {
 type CachedItemRepo struct {
 	itemBackend  ItemBackend
 	cacheBackend clients.CacheClient
+	ttls         ItemCacheTTLs
+}
+
+// cachedItem is the cache entry of an item
+type cachedItem struct {
+	Item       model.Item
+	SoftExpiry time.Time
 }
 
 // NewCachedItemRepo cached backed item repository
 func NewCachedItemRepo(itemBackend ItemBackend, cacheBackend clients.CacheClient) ItemRepo {
+	return NewCachedItemRepoWithTTLs(itemBackend, cacheBackend, DefaultItemCacheTTLs)
+}
+
+// NewCachedItemRepoWithTTLs cached backed item repository with per item type ttls
+func NewCachedItemRepoWithTTLs(itemBackend ItemBackend, cacheBackend clients.CacheClient, ttls ItemCacheTTLs) ItemRepo {
 	return &CachedItemRepo{
 		itemBackend:  itemBackend,
 		cacheBackend: cacheBackend,
+		ttls:         ttls,
 	}
 }
 
@@ -34,49 +86,157 @@ func NewCachedItemRepo(itemBackend ItemBackend, cacheBackend clients.CacheClient
 func (c *CachedItemRepo) Get(ctx context.Context, itemIds []int) ([]model.Item, error) {
 	log.Debug(ctx, itemIds)
 	log.Info(ctx, "number of items to lookup", len(itemIds))
-	resultItems := make([]model.Item, 0)
 
-	needToHydrateItemIdsSet := make(map[int]bool, 0)
-	keys := make([]string, 0)
-	for _, ID := range itemIds {
+	uniqueItemIds := dedupe(itemIds)
+	itemsByID := make(map[int]model.Item, len(uniqueItemIds))
+
+	keys := make([]string, 0, len(uniqueItemIds))
+	for _, ID := range uniqueItemIds {
 		keys = append(keys, itemCacheKey(ID))
-		needToHydrateItemIdsSet[ID] = true
 	}
 	log.Debug(ctx, "cache keys to lookup", keys)
 	log.Info(ctx, "cache keys to lookup", len(keys))
 
+	now := time.Now()
+	failuresByID := make(map[int]model.Failure)
+	staleItemIds := make([]int, 0)
 	cacheResultBytes, err := c.cacheBackend.MultiGet(ctx, keys)
 	for _, itemBytes := range cacheResultBytes {
-		var result model.Item
-		err = clients.FromBytes(itemBytes, &result)
+		var result cachedItem
+		err = clients.FromBytes(c.cacheBackend.Codec(), itemBytes, &result)
+		if err == clients.ErrCacheMiss {
+			log.Debug(ctx, "cache entry of another codec or schema version")
+			continue
+		}
 		if err != nil {
 			log.Error(ctx, "failed to deserialize", err)
+			continue
+		}
+
+		if result.Item.Missing != "" {
+			failuresByID[result.Item.ID] = model.Failure{ID: result.Item.ID, Reason: result.Item.Missing}
+		}
+
+		if now.After(result.SoftExpiry) {
+			log.Info(ctx, "stale cache hit", result.Item.ID)
+			staleItemIds = append(staleItemIds, result.Item.ID)
 		} else {
-			log.Info(ctx, "cache hit", result.ID)
-			resultItems = append(resultItems, result)
-			delete(needToHydrateItemIdsSet, result.ID)
+			log.Info(ctx, "cache hit", result.Item.ID)
 		}
+		itemsByID[result.Item.ID] = result.Item
+	}
+
+	if len(staleItemIds) > 0 {
+		go c.revalidate(ctx, staleItemIds)
 	}
 
 	needToHydrateItemIds := make([]int, 0)
-	for ID := range needToHydrateItemIdsSet {
-		needToHydrateItemIds = append(needToHydrateItemIds, ID)
+	for _, ID := range uniqueItemIds {
+		if _, ok := itemsByID[ID]; !ok {
+			needToHydrateItemIds = append(needToHydrateItemIds, ID)
+		}
 	}
 
-	itemChan, errChan := c.itemBackend.HydrateItem(ctx, needToHydrateItemIds)
+	log.Debug(ctx, "items still needed to hydrate", needToHydrateItemIds)
+	log.Info(ctx, "items still needed to hydrate", len(needToHydrateItemIds))
+	hydratedItems, hydrateFailures := c.hydrate(ctx, needToHydrateItemIds, true)
+	for _, item := range hydratedItems {
+		itemsByID[item.ID] = item
+	}
+	for _, failure := range hydrateFailures {
+		failuresByID[failure.ID] = failure
+	}
+
+	resultItems := make([]model.Item, 0, len(uniqueItemIds))
+	failures := make([]model.Failure, 0, len(failuresByID))
+	for _, ID := range uniqueItemIds {
+		item, ok := itemsByID[ID]
+		if !ok {
+			// the backend failed without attributing an error to the item
+			item = model.NewMissingItem(ID, model.MissingError)
+			failuresByID[ID] = model.Failure{ID: ID, Reason: item.Missing}
+		}
+		resultItems = append(resultItems, item)
+		if failure, ok := failuresByID[ID]; ok {
+			failures = append(failures, failure)
+		}
+	}
+
+	if len(failures) > 0 {
+		return resultItems, &PartialError{Failures: failures}
+	}
+	return resultItems, nil
+}
+
+// dedupe itemIds keeping the first of repeated ids
+func dedupe(itemIds []int) []int {
+	seen := make(map[int]bool, len(itemIds))
+	result := make([]int, 0, len(itemIds))
+	for _, ID := range itemIds {
+		if !seen[ID] {
+			seen[ID] = true
+			result = append(result, ID)
+		}
+	}
+	return result
+}
+
+// revalidate refreshes stale items, outliving the request that found them
+func (c *CachedItemRepo) revalidate(ctx context.Context, itemIds []int) {
+	ctx, cancel := context.WithTimeout(detach(ctx), revalidateTimeout)
+	defer cancel()
+
+	// keep serving the stale items rather than caching a failure over them
+	log.Info(ctx, "revalidating stale items", len(itemIds))
+	c.hydrate(ctx, itemIds, false)
+}
+
+// hydrate fetches items from the backend and writes them to the cache
+// Items that fail to hydrate are returned as Missing placeholders, along with their failure,
+// and are negatively cached when cacheFailures is set
+func (c *CachedItemRepo) hydrate(ctx context.Context, itemIds []int, cacheFailures bool) ([]model.Item, []model.Failure) {
+	resultItems := make([]model.Item, 0, len(itemIds))
+	failures := make([]model.Failure, 0)
+	if len(itemIds) == 0 {
+		return resultItems, failures
+	}
+
+	itemChan, errChan := c.itemBackend.HydrateItem(ctx, itemIds)
 	defer close(itemChan)
 	defer close(errChan)
 
-	log.Debug(ctx, "items still needed to hydrate", needToHydrateItemIds)
-	log.Info(ctx, "items still needed to hydrate", len(needToHydrateItemIds))
-	for range needToHydrateItemIds {
+	for range itemIds {
 		select {
 		case err, ok := <-errChan:
-			if err == context.Canceled {
-				log.Error(ctx, "hydrate item was cancelled", err, ok)
+			itemID, isItemErr := errItemID(err)
+			if !isItemErr {
+				log.Error(ctx, "failed to hydrate item", err, ok)
 				continue
 			}
-			log.Error(ctx, "failed to hydrate item", err, ok)
+
+			var missing model.Item
+			switch {
+			case isContextErr(err):
+				log.Error(ctx, "hydrate item was cancelled", err, ok)
+				missing = model.NewMissingItem(itemID, model.MissingError)
+			case cause(err) == clients.ErrCircuitOpen:
+				log.Info(ctx, "upstream unavailable, failing fast", itemID)
+				missing = model.NewMissingItem(itemID, model.MissingError)
+			case isNotFound(err):
+				log.Info(ctx, "item not found", itemID)
+				missing = model.NewMissingItem(itemID, model.MissingNotFound)
+				if cacheFailures {
+					c.write(ctx, missing, negativeCacheTTL)
+				}
+			default:
+				log.Error(ctx, "failed to hydrate item", err, ok)
+				missing = model.NewMissingItem(itemID, model.MissingError)
+				if cacheFailures {
+					c.write(ctx, missing, failedCacheTTL)
+				}
+			}
+			resultItems = append(resultItems, missing)
+			failures = append(failures, model.Failure{ID: missing.ID, Reason: missing.Missing, Message: cause(err).Error()})
 
 		case r, ok := <-itemChan:
 			if !ok {
@@ -84,19 +244,35 @@ func (c *CachedItemRepo) Get(ctx context.Context, itemIds []int) ([]model.Item,
 				continue
 			}
 
-			key := itemCacheKey(r.ID)
-			err := c.cacheBackend.Set(ctx, key, &r, cacheDurationTTL)
-			if err != nil {
-				log.Error(ctx, "failed to write to cache", key, err)
-			} else {
-				log.Debug(ctx, "wrote to cache", key)
-			}
-
+			c.write(ctx, r, c.itemTTL(r))
 			resultItems = append(resultItems, r)
 		}
 	}
 
-	return resultItems, nil
+	return resultItems, failures
+}
+
+// itemTTL of a hydrated item, deleted and dead items are negatively cached
+func (c *CachedItemRepo) itemTTL(item model.Item) CacheTTL {
+	if item.Deleted || item.Dead {
+		return negativeCacheTTL
+	}
+	return c.ttls.ttl(item.Type)
+}
+
+func (c *CachedItemRepo) write(ctx context.Context, item model.Item, ttl CacheTTL) {
+	entry := cachedItem{
+		Item:       item,
+		SoftExpiry: time.Now().Add(ttl.Soft),
+	}
+
+	key := itemCacheKey(item.ID)
+	err := c.cacheBackend.Set(ctx, key, &entry, ttl.Hard)
+	if err != nil {
+		log.Error(ctx, "failed to write to cache", key, err)
+	} else {
+		log.Debug(ctx, "wrote to cache", key)
+	}
 }
 
 func itemCacheKey(id int) string {
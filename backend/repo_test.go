@@ -0,0 +1,130 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cevaris/hnapi/clients"
+	"github.com/cevaris/hnapi/model"
+)
+
+type notFoundItemBackend struct {
+	calls int32
+}
+
+func (b *notFoundItemBackend) HydrateItem(ctx context.Context, itemIds []int) (chan model.Item, chan error) {
+	itemChan := make(chan model.Item, len(itemIds))
+	errChan := make(chan error, len(itemIds))
+	for _, itemID := range itemIds {
+		atomic.AddInt32(&b.calls, 1)
+		errChan <- &ErrNotFound{Resource: "item", ItemID: itemID}
+	}
+	return itemChan, errChan
+}
+
+func TestCachedItemRepoCachesNotFoundItems(t *testing.T) {
+	ctx := context.Background()
+	itemBackend := &notFoundItemBackend{}
+	itemRepo := NewCachedItemRepo(itemBackend, clients.NewLRUCacheClient(10, clients.NewJSONCodec()))
+
+	for i := 0; i < 2; i++ {
+		items, err := itemRepo.Get(ctx, []int{1})
+		if len(items) != 1 || items[0].ID != 1 || items[0].Missing != model.MissingNotFound {
+			t.Errorf("expected not found placeholder, got: %v", items)
+		}
+		if partialErr, ok := err.(*PartialError); !ok || len(partialErr.Failures) != 1 {
+			t.Errorf("expected a partial error, got: %v", err)
+		}
+	}
+
+	if calls := atomic.LoadInt32(&itemBackend.calls); calls != 1 {
+		t.Errorf("expected not found item to be cached, got %d calls", calls)
+	}
+}
+
+func TestCachedItemRepoServesStaleWhileRevalidating(t *testing.T) {
+	ctx := context.Background()
+	itemBackend := &countingItemBackend{}
+	ttls := ItemCacheTTLs{"": {Soft: 0, Hard: time.Minute}}
+	itemRepo := NewCachedItemRepoWithTTLs(itemBackend, clients.NewLRUCacheClient(10, clients.NewJSONCodec()), ttls)
+
+	items, _ := itemRepo.Get(ctx, []int{1})
+	if len(items) != 1 || atomic.LoadInt32(&itemBackend.calls) != 1 {
+		t.Fatalf("expected item to be hydrated, got: %v", items)
+	}
+
+	items, _ = itemRepo.Get(ctx, []int{1})
+	if len(items) != 1 || items[0].ID != 1 {
+		t.Fatalf("expected stale item to be served, got: %v", items)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&itemBackend.calls) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected stale item to be revalidated, got %d calls", atomic.LoadInt32(&itemBackend.calls))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCachedItemRepoServesFreshFromCache(t *testing.T) {
+	ctx := context.Background()
+	itemBackend := &countingItemBackend{}
+	itemRepo := NewCachedItemRepo(itemBackend, clients.NewLRUCacheClient(10, clients.NewJSONCodec()))
+
+	itemRepo.Get(ctx, []int{1, 2})
+	items, _ := itemRepo.Get(ctx, []int{1, 2})
+	time.Sleep(10 * time.Millisecond)
+
+	if len(items) != 2 || atomic.LoadInt32(&itemBackend.calls) != 2 {
+		t.Errorf("expected fresh items from cache, got: %v after %d calls", items, atomic.LoadInt32(&itemBackend.calls))
+	}
+}
+
+// failingItemBackend fails every item without attributing errors to them
+type failingItemBackend struct{}
+
+func (b *failingItemBackend) HydrateItem(ctx context.Context, itemIds []int) (chan model.Item, chan error) {
+	itemChan := make(chan model.Item, len(itemIds))
+	errChan := make(chan error, len(itemIds))
+	for range itemIds {
+		errChan <- errors.New("boom")
+	}
+	return itemChan, errChan
+}
+
+func TestCachedItemRepoReturnsItemsInRequestOrder(t *testing.T) {
+	ctx := context.Background()
+	itemBackend := &countingItemBackend{}
+	itemRepo := NewCachedItemRepo(itemBackend, clients.NewLRUCacheClient(10, clients.NewJSONCodec()))
+
+	itemRepo.Get(ctx, []int{3})
+	items, err := itemRepo.Get(ctx, []int{1, 3, 2, 1, 3})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	ids := make([]int, 0)
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 3 || ids[2] != 2 {
+		t.Errorf("expected deduped items in request order, got: %v", ids)
+	}
+}
+
+func TestCachedItemRepoPlaceholdersUnattributedFailures(t *testing.T) {
+	ctx := context.Background()
+	itemRepo := NewCachedItemRepo(&failingItemBackend{}, clients.NewLRUCacheClient(10, clients.NewJSONCodec()))
+
+	items, err := itemRepo.Get(ctx, []int{2, 1})
+	if len(items) != 2 || items[0].ID != 2 || items[1].ID != 1 || items[0].Missing != model.MissingError {
+		t.Errorf("expected failed placeholders in request order, got: %v", items)
+	}
+	if partialErr, ok := err.(*PartialError); !ok || len(partialErr.Failures) != 2 || partialErr.Failures[0].ID != 2 {
+		t.Errorf("expected a partial error in request order, got: %v", err)
+	}
+}
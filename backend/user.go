@@ -0,0 +1,92 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/cevaris/hnapi/clients"
+	"github.com/cevaris/hnapi/model"
+)
+
+var userCacheDurationTTL = time.Minute * time.Duration(5)
+
+// UserBackend hydrates Users
+type UserBackend interface {
+	HydrateUser(ctx context.Context, userID string) (model.User, error)
+}
+
+// FireBaseUserBackend firebase backed user client
+type FireBaseUserBackend struct {
+	client   clients.HTTPClient
+	baseURLs []string
+}
+
+// NewFireBaseUserBackend constructs a new user backend
+// Users are fetched from the first of baseURLs that responds, defaulting to DefaultBaseURL.
+func NewFireBaseUserBackend(httpClient clients.HTTPClient, baseURLs ...string) UserBackend {
+	return &FireBaseUserBackend{client: httpClient, baseURLs: orDefaultBaseURLs(baseURLs)}
+}
+
+// HydrateUser fetches a single user
+func (f *FireBaseUserBackend) HydrateUser(ctx context.Context, userID string) (model.User, error) {
+	path := fmt.Sprintf("/user/%s.json", url.PathEscape(userID))
+
+	var user model.User
+	err := getJSON(ctx, f.client, f.baseURLs, path, fmt.Sprintf("user %s", userID), 0, &user)
+	if err != nil {
+		log.Error(ctx, "failed to hydrate user", userID, err)
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// UserRepo hydrates users
+type UserRepo interface {
+	Get(context.Context, string) (model.User, error)
+}
+
+// CachedUserRepo hydrates and caches Users
+type CachedUserRepo struct {
+	userBackend  UserBackend
+	cacheBackend clients.CacheClient
+}
+
+// NewCachedUserRepo cached backed user repository
+func NewCachedUserRepo(userBackend UserBackend, cacheBackend clients.CacheClient) UserRepo {
+	return &CachedUserRepo{
+		userBackend:  userBackend,
+		cacheBackend: cacheBackend,
+	}
+}
+
+// Get cached user
+func (c *CachedUserRepo) Get(ctx context.Context, userID string) (model.User, error) {
+	key := userCacheKey(userID)
+
+	var user model.User
+	err := c.cacheBackend.Get(ctx, key, (*cachedUser)(&user))
+	if err == nil {
+		log.Info(ctx, "cache hit", key)
+		return user, nil
+	}
+
+	user, err = c.userBackend.HydrateUser(ctx, userID)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	err = c.cacheBackend.Set(ctx, key, (*cachedUser)(&user), userCacheDurationTTL)
+	if err != nil {
+		log.Error(ctx, "failed to write to cache", key, err)
+	} else {
+		log.Debug(ctx, "wrote to cache", key)
+	}
+
+	return user, nil
+}
+
+func userCacheKey(id string) string {
+	return fmt.Sprintf("user:%s", id)
+}
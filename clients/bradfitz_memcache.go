@@ -10,27 +10,31 @@ import (
 // MemcacheClient blah
 type bradfitzMemcacheClient struct {
 	client *memcache.Client
+	codec  Codec
 }
 
 // NewBradfitzMemcacheClient new client
-func NewBradfitzMemcacheClient(hostname string) CacheClient {
+func NewBradfitzMemcacheClient(hostname string, codec Codec) CacheClient {
 	client := memcache.New(hostname)
-	return &bradfitzMemcacheClient{client: client}
+	return &bradfitzMemcacheClient{client: client, codec: codec}
+}
+
+// Codec entries are serialized with
+func (m *bradfitzMemcacheClient) Codec() Codec {
+	return m.codec
 }
 
 // MultiGet data from cache
-func (m *bradfitzMemcacheClient) MultiGet(ctx context.Context, keys []string) ([][]byte, error) {
+func (m *bradfitzMemcacheClient) MultiGet(ctx context.Context, keys []string) (map[string][]byte, error) {
 	cacheItemMap, err := m.client.GetMulti(keys)
 	if err != nil {
 		log.Error(ctx, "failed fetching", keys, err)
 		return nil, err
 	}
 
-	result := make([][]byte, len(cacheItemMap))
-	var i = 0
-	for _, cacheItem := range cacheItemMap {
-		result[i] = cacheItem.Value
-		i++
+	result := make(map[string][]byte, len(cacheItemMap))
+	for key, cacheItem := range cacheItemMap {
+		result[key] = cacheItem.Value
 	}
 
 	return result, nil
@@ -47,7 +51,7 @@ func (m *bradfitzMemcacheClient) Get(ctx context.Context, key string, result int
 		return err
 	}
 
-	err = FromBytes(cacheItem.Value, result)
+	err = FromBytes(m.codec, cacheItem.Value, result)
 	if err != nil {
 		log.Error(ctx, "failed to deserialize memcached data for key", key, err)
 		return err
@@ -58,16 +62,20 @@ func (m *bradfitzMemcacheClient) Get(ctx context.Context, key string, result int
 
 // Set data in cache
 func (m *bradfitzMemcacheClient) Set(ctx context.Context, key string, data interface{}, ttl time.Duration) error {
-	bytes, err := ToBytes(data)
+	bytes, err := ToBytes(m.codec, data)
 	if err != nil {
 		log.Error(ctx, "failed to serialize memcached data for key", key, data, err)
 		return err
 	}
+	return m.SetBytes(ctx, key, bytes, ttl)
+}
 
+// SetBytes stores already serialized data
+func (m *bradfitzMemcacheClient) SetBytes(ctx context.Context, key string, bytes []byte, ttl time.Duration) error {
 	item := memcache.Item{
 		Key:        key,
 		Value:      bytes,
-		Expiration: int32(ttl),
+		Expiration: int32(ttl.Seconds()),
 	}
 	return m.client.Set(&item)
 }
@@ -0,0 +1,226 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without making a request while the breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState of a CircuitBreaker
+type BreakerState string
+
+// Breaker states
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerConfig configures a CircuitBreaker
+type BreakerConfig struct {
+	// FailureThreshold consecutive failures open the breaker
+	FailureThreshold int
+	// OpenTimeout the breaker stays open before letting trial requests through
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests concurrent trial requests while half open
+	HalfOpenMaxRequests int
+}
+
+// DefaultBreakerConfig opens after 10 consecutive failures, for 30 seconds
+var DefaultBreakerConfig = BreakerConfig{
+	FailureThreshold:    10,
+	OpenTimeout:         30 * time.Second,
+	HalfOpenMaxRequests: 1,
+}
+
+// CircuitBreaker tracks upstream health, shared by every client of an upstream
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	config   BreakerConfig
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trials   int
+	now      func() time.Time
+	// generation is bumped on every state change, so outcomes of requests
+	// admitted before the last change are not mistaken for current ones
+	generation int
+}
+
+// admission of an allowed request, the generation it was allowed in and
+// whether it is a half open trial
+type admission struct {
+	generation int
+	trial      bool
+}
+
+// BreakerStats snapshot of a CircuitBreaker
+type BreakerStats struct {
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutiveFailures"`
+	OpenedAt            *time.Time   `json:"openedAt,omitempty"`
+}
+
+// NewCircuitBreaker constructs a closed breaker
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{config: config, state: BreakerClosed, now: time.Now}
+}
+
+// Stats returns the current state
+func (b *CircuitBreaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	stats := BreakerStats{State: b.state, ConsecutiveFailures: b.failures}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		stats.OpenedAt = &openedAt
+	}
+	return stats
+}
+
+// allow reports whether a request may be made and admits it,
+// allowed requests must be followed by done or cancel
+func (b *CircuitBreaker) allow() (admission, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	switch b.state {
+	case BreakerOpen:
+		return admission{}, false
+	case BreakerHalfOpen:
+		if b.trials >= b.config.HalfOpenMaxRequests {
+			return admission{}, false
+		}
+		b.trials++
+		return admission{generation: b.generation, trial: true}, true
+	}
+	return admission{generation: b.generation}, true
+}
+
+// done records the outcome of an allowed request
+// Requests admitted before the breaker last changed state say nothing about
+// the current state, only a half open trial may close the breaker.
+func (b *CircuitBreaker) done(a admission, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.release(a)
+	if a.generation != b.generation {
+		return
+	}
+
+	if success {
+		if a.trial {
+			b.setState(BreakerClosed)
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.setState(BreakerOpen)
+		b.openedAt = b.now()
+	}
+}
+
+// cancel releases an allowed request without recording its outcome
+func (b *CircuitBreaker) cancel(a admission) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.release(a)
+}
+
+// release frees the slot of a trial request, must be called holding mu
+func (b *CircuitBreaker) release(a admission) {
+	if a.trial && a.generation == b.generation && b.trials > 0 {
+		b.trials--
+	}
+}
+
+// refresh moves an open breaker to half open once its timeout passed, must be called holding mu
+func (b *CircuitBreaker) refresh() {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.setState(BreakerHalfOpen)
+		b.trials = 0
+	}
+}
+
+// setState starts a new generation, must be called holding mu
+func (b *CircuitBreaker) setState(state BreakerState) {
+	b.state = state
+	b.generation++
+}
+
+// CircuitBreakers holds a breaker per upstream host, so mirrors fail independently
+type CircuitBreakers struct {
+	mu       sync.Mutex
+	config   BreakerConfig
+	breakers map[string]*CircuitBreaker
+}
+
+// NewCircuitBreakers constructs closed breakers on demand
+func NewCircuitBreakers(config BreakerConfig) *CircuitBreakers {
+	return &CircuitBreakers{config: config, breakers: make(map[string]*CircuitBreaker)}
+}
+
+// For returns the breaker of host
+func (b *CircuitBreakers) For(host string) *CircuitBreaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	breaker, ok := b.breakers[host]
+	if !ok {
+		breaker = NewCircuitBreaker(b.config)
+		b.breakers[host] = breaker
+	}
+	return breaker
+}
+
+// Stats returns the current state of every host
+func (b *CircuitBreakers) Stats() map[string]BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := make(map[string]BreakerStats, len(b.breakers))
+	for host, breaker := range b.breakers {
+		stats[host] = breaker.Stats()
+	}
+	return stats
+}
+
+type breakerHTTPClient struct {
+	client   HTTPClient
+	breakers *CircuitBreakers
+}
+
+// NewBreakerHTTPClient fails fast with ErrCircuitOpen while the breaker of a host is open
+func NewBreakerHTTPClient(client HTTPClient, breakers *CircuitBreakers) HTTPClient {
+	return &breakerHTTPClient{client: client, breakers: breakers}
+}
+
+func (c *breakerHTTPClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	breaker := c.breakers.For(req.URL.Host)
+	admitted, allowed := breaker.allow()
+	if !allowed {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := c.client.Do(ctx, req)
+	if err != nil && ctx.Err() != nil {
+		// the caller went away, says nothing about upstream health
+		breaker.cancel(admitted)
+		return resp, err
+	}
+	breaker.done(admitted, err == nil && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests)
+	return resp, err
+}
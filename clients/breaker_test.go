@@ -0,0 +1,105 @@
+package clients
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	breakers := NewCircuitBreakers(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+	breaker := breakers.For("hn")
+	breaker.now = func() time.Time { return now }
+
+	stub := &stubHTTPClient{statuses: []int{500, 500, 200}}
+	client := NewBreakerHTTPClient(stub, breakers)
+
+	Get(ctx, client, "http://hn")
+	Get(ctx, client, "http://hn")
+	if state := breaker.Stats().State; state != BreakerOpen {
+		t.Fatalf("expected breaker to open, got: %v", state)
+	}
+
+	if _, err := Get(ctx, client, "http://hn"); err != ErrCircuitOpen || stub.calls != 2 {
+		t.Errorf("expected to fail fast, got: %v after %d calls", err, stub.calls)
+	}
+
+	now = now.Add(time.Minute)
+	if state := breaker.Stats().State; state != BreakerHalfOpen {
+		t.Fatalf("expected breaker to half open, got: %v", state)
+	}
+
+	resp, err := Get(ctx, client, "http://hn")
+	if err != nil || resp.StatusCode != 200 {
+		t.Errorf("expected trial request to go through, got: %v", err)
+	}
+	if state := breaker.Stats().State; state != BreakerClosed {
+		t.Errorf("expected breaker to close, got: %v", state)
+	}
+}
+
+func TestCircuitBreakerReopensOnFailedTrial(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	breakers := NewCircuitBreakers(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+	breaker := breakers.For("hn")
+	breaker.now = func() time.Time { return now }
+	client := NewBreakerHTTPClient(&stubHTTPClient{statuses: []int{503}}, breakers)
+
+	Get(ctx, client, "http://hn")
+	now = now.Add(time.Minute)
+	Get(ctx, client, "http://hn")
+
+	if state := breaker.Stats().State; state != BreakerOpen {
+		t.Errorf("expected breaker to reopen, got: %v", state)
+	}
+}
+
+func TestCircuitBreakersArePerHost(t *testing.T) {
+	ctx := context.Background()
+	breakers := NewCircuitBreakers(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+	stub := &stubHTTPClient{statuses: []int{500, 200}}
+	client := NewBreakerHTTPClient(stub, breakers)
+
+	Get(ctx, client, "http://primary/v0/item/1.json")
+	if _, err := Get(ctx, client, "http://mirror/v0/item/1.json"); err != nil {
+		t.Errorf("expected mirror to be unaffected, got: %v", err)
+	}
+
+	stats := breakers.Stats()
+	if stats["primary"].State != BreakerOpen || stats["mirror"].State != BreakerClosed {
+		t.Errorf("unexpected states, got: %v", stats)
+	}
+}
+
+func TestCircuitBreakerIgnoresRequestsAdmittedBeforeItOpened(t *testing.T) {
+	now := time.Unix(0, 0)
+	breaker := NewCircuitBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+	breaker.now = func() time.Time { return now }
+
+	slow, _ := breaker.allow()
+	slower, _ := breaker.allow()
+	failed, _ := breaker.allow()
+	breaker.done(failed, false)
+
+	breaker.done(slow, true)
+	if state := breaker.Stats().State; state != BreakerOpen {
+		t.Errorf("expected success admitted before opening to keep the breaker open, got: %v", state)
+	}
+
+	now = now.Add(time.Minute)
+	trial, allowed := breaker.allow()
+	if !allowed || !trial.trial {
+		t.Fatalf("expected a half open trial, got: %v", trial)
+	}
+	breaker.done(slower, true)
+	if state := breaker.Stats().State; state != BreakerHalfOpen {
+		t.Errorf("expected success admitted before opening to keep the breaker half open, got: %v", state)
+	}
+	breaker.done(trial, true)
+	if state := breaker.Stats().State; state != BreakerClosed {
+		t.Errorf("expected the trial to close the breaker, got: %v", state)
+	}
+}
@@ -1,36 +1,27 @@
 package clients
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
+	"errors"
 	"time"
 
 	"github.com/cevaris/timber"
 )
 
-// User proto for serialization https://stackoverflow.com/questions/37618399/efficient-go-serialization-of-struct-to-disk
-
 var log = timber.NewGoogleLogger()
 
+// ErrCacheMiss is returned by in-process caches when a key is not found,
+// and by FromBytes for entries written with another codec or schema version
+var ErrCacheMiss = errors.New("cache: miss")
+
 // CacheClient is the common cache interface
+// Values are serialized with the codec the client was constructed with,
+// MultiGet returns them still encoded, decode them with FromBytes and Codec.
+// SetBytes stores a value already encoded by ToBytes with Codec.
 type CacheClient interface {
 	Get(context.Context, string, interface{}) error
-	MultiGet(context.Context, []string) ([][]byte, error)
+	MultiGet(context.Context, []string) (map[string][]byte, error)
 	Set(context.Context, string, interface{}, time.Duration) error
-}
-
-// ToBytes niavely converts a value to []byte
-func ToBytes(data interface{}) ([]byte, error) {
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err := enc.Encode(data)
-	return buf.Bytes(), err
-}
-
-// FromBytes niavely converts bytes to some interface
-func FromBytes(byteBuff []byte, result interface{}) error {
-	buf := bytes.NewReader(byteBuff)
-	enc := gob.NewDecoder(buf)
-	return enc.Decode(result)
+	SetBytes(context.Context, string, []byte, time.Duration) error
+	Codec() Codec
 }
@@ -27,10 +27,10 @@ func TestByteConversions(t *testing.T) {
 	}
 
 	for i := 0; i < 100; i++ {
-		testBytes, _ := ToBytes(expectedStruct)
+		testBytes, _ := ToBytes(NewJSONCodec(), expectedStruct)
 
 		var actualStruct TestStruct
-		FromBytes(testBytes, &actualStruct)
+		FromBytes(NewJSONCodec(), testBytes, &actualStruct)
 
 		if !cmp.Equal(expectedStruct, actualStruct) {
 			t.Errorf("byte/struct conversion failed, got: %v, want: %v.", actualStruct, expectedStruct)
@@ -0,0 +1,95 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+// CacheSchemaVersion of cached values, bump it when a cached type changes
+// incompatibly so entries written by older deploys are treated as misses
+const CacheSchemaVersion = 1
+
+// Codec serializes cache entries
+type Codec interface {
+	// ID tells entries of different codecs apart, between 1 and 15
+	ID() byte
+	Marshal(data interface{}) ([]byte, error)
+	Unmarshal(b []byte, result interface{}) error
+}
+
+// NewCodec by name, one of json|proto|msgpack
+func NewCodec(name string) (Codec, error) {
+	switch name {
+	case "json":
+		return NewJSONCodec(), nil
+	case "proto":
+		return NewProtoCodec(), nil
+	case "msgpack":
+		return NewMsgpackCodec(), nil
+	default:
+		return nil, fmt.Errorf("unknown codec '%s'", name)
+	}
+}
+
+// versionByte prefixes every entry written with c
+func versionByte(c Codec) byte {
+	return c.ID()<<4 | CacheSchemaVersion
+}
+
+// ToBytes encodes a value with codec, prefixed by its version byte
+func ToBytes(codec Codec, data interface{}) ([]byte, error) {
+	b, err := codec.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{versionByte(codec)}, b...), nil
+}
+
+// FromBytes decodes bytes written by ToBytes with the same codec
+// Entries of another codec or schema version are an ErrCacheMiss.
+func FromBytes(codec Codec, byteBuff []byte, result interface{}) error {
+	if len(byteBuff) == 0 || byteBuff[0] != versionByte(codec) {
+		return ErrCacheMiss
+	}
+	return codec.Unmarshal(byteBuff[1:], result)
+}
+
+type jsonCodec struct{}
+
+// NewJSONCodec encodes entries as json, fields are matched by their json tags
+func NewJSONCodec() Codec {
+	return jsonCodec{}
+}
+
+func (jsonCodec) ID() byte {
+	return 1
+}
+
+func (jsonCodec) Marshal(data interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+func (jsonCodec) Unmarshal(b []byte, result interface{}) error {
+	return json.Unmarshal(b, result)
+}
+
+type msgpackCodec struct{}
+
+// NewMsgpackCodec encodes entries as msgpack, fields are matched by name
+func NewMsgpackCodec() Codec {
+	return msgpackCodec{}
+}
+
+func (msgpackCodec) ID() byte {
+	return 3
+}
+
+func (msgpackCodec) Marshal(data interface{}) ([]byte, error) {
+	return msgpack.Marshal(data)
+}
+
+func (msgpackCodec) Unmarshal(b []byte, result interface{}) error {
+	return msgpack.Unmarshal(b, result)
+}
@@ -0,0 +1,76 @@
+package clients
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCodecsRoundTrip(t *testing.T) {
+	expectedStruct := TestStruct{
+		TestSlice: []int{2, 3, 5},
+		Nested:    NestedStruct{"nestedTestValue"},
+		TestMap:   map[string]NestedStruct{"testKey1": {"testValue1"}},
+	}
+	for _, name := range []string{"json", "msgpack"} {
+		codec, err := NewCodec(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testBytes, err := ToBytes(codec, expectedStruct)
+		if err != nil {
+			t.Fatalf("%s failed to encode: %v", name, err)
+		}
+		var actualStruct TestStruct
+		err = FromBytes(codec, testBytes, &actualStruct)
+		if err != nil || !cmp.Equal(expectedStruct, actualStruct) {
+			t.Errorf("%s round trip failed, got: %v %v, want: %v", name, actualStruct, err, expectedStruct)
+		}
+	}
+}
+
+func TestFromBytesTreatsVersionMismatchAsMiss(t *testing.T) {
+	testBytes, _ := ToBytes(NewJSONCodec(), []int{1, 2})
+
+	var actual []int
+	if err := FromBytes(NewMsgpackCodec(), testBytes, &actual); err != ErrCacheMiss {
+		t.Errorf("expected entry of another codec to miss, got: %v", err)
+	}
+	if err := FromBytes(NewMsgpackCodec(), []byte{0x01}, &actual); err != ErrCacheMiss {
+		t.Errorf("expected entry of another schema version to miss, got: %v", err)
+	}
+}
+
+func TestProtoCodecRequiresProtoMarshaler(t *testing.T) {
+	if _, err := NewProtoCodec().Marshal([]int{1}); err == nil {
+		t.Errorf("expected proto codec to reject values that are not ProtoMarshalers")
+	}
+}
+
+func TestProtoCodecUnmarshal(t *testing.T) {
+	codec := NewProtoCodec()
+	testBytes, err := ToBytes(codec, wrapperspb.String("hn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual wrapperspb.StringValue
+	if err := FromBytes(codec, testBytes, &actual); err != nil || actual.Value != "hn" {
+		t.Errorf("expected message to round trip, got: %v %v", actual.Value, err)
+	}
+
+	jsonVersion := append([]byte{versionByte(NewJSONCodec())}, testBytes[1:]...)
+	if err := FromBytes(codec, jsonVersion, &actual); err != ErrCacheMiss {
+		t.Errorf("expected entry of the json codec to miss, got: %v", err)
+	}
+	if err := FromBytes(codec, testBytes[:len(testBytes)-1], &actual); err == nil || err == ErrCacheMiss {
+		t.Errorf("expected truncated entry to fail decoding, got: %v", err)
+	}
+
+	var mismatched []string
+	if err := FromBytes(codec, testBytes, &mismatched); err == nil {
+		t.Errorf("expected decoding into a value that is not a message to fail")
+	}
+}
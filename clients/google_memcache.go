@@ -10,28 +10,32 @@ import (
 
 // GoogleMemcacheClient blah
 type googleMemcacheClient struct {
+	codec Codec
 }
 
 // NewGoogleMemcacheClient new client
 // Delegates to client config to underlying google app engine memcache client
-func NewGoogleMemcacheClient() CacheClient {
-	return &googleMemcacheClient{}
+func NewGoogleMemcacheClient(codec Codec) CacheClient {
+	return &googleMemcacheClient{codec: codec}
+}
+
+// Codec entries are serialized with
+func (m *googleMemcacheClient) Codec() Codec {
+	return m.codec
 }
 
 // MultiGet data from cache
-func (m *googleMemcacheClient) MultiGet(ctx context.Context, keys []string) ([][]byte, error) {
+func (m *googleMemcacheClient) MultiGet(ctx context.Context, keys []string) (map[string][]byte, error) {
 	cacheItemMap, err := gmemcache.GetMulti(ctx, keys)
 	if err != nil {
 		log.Error(ctx, "failed fetching", keys, err)
 		return nil, err
 	}
 
-	result := make([][]byte, len(cacheItemMap))
-	var i = 0
+	result := make(map[string][]byte, len(cacheItemMap))
 	log.Info(ctx, "cache lookup found", len(cacheItemMap), "of", len(keys))
-	for _, cacheItem := range cacheItemMap {
-		result[i] = cacheItem.Value
-		i++
+	for key, cacheItem := range cacheItemMap {
+		result[key] = cacheItem.Value
 	}
 
 	return result, nil
@@ -48,7 +52,7 @@ func (m *googleMemcacheClient) Get(ctx context.Context, key string, result inter
 		return err
 	}
 
-	err = FromBytes(cacheItem.Value, result)
+	err = FromBytes(m.codec, cacheItem.Value, result)
 	if err != nil {
 		log.Error(ctx, "failed to deserialize memcached data for key", key, err)
 		return err
@@ -59,12 +63,16 @@ func (m *googleMemcacheClient) Get(ctx context.Context, key string, result inter
 
 // Set data in cache
 func (m *googleMemcacheClient) Set(ctx context.Context, key string, data interface{}, ttl time.Duration) error {
-	bytes, err := ToBytes(data)
+	bytes, err := ToBytes(m.codec, data)
 	if err != nil {
 		log.Error(ctx, "failed to serialize memcached data for key", key, data, err)
 		return err
 	}
+	return m.SetBytes(ctx, key, bytes, ttl)
+}
 
+// SetBytes stores already serialized data
+func (m *googleMemcacheClient) SetBytes(ctx context.Context, key string, bytes []byte, ttl time.Duration) error {
 	item := gmemcache.Item{
 		Key:        key,
 		Value:      bytes,
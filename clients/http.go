@@ -9,21 +9,32 @@ import (
 )
 
 // HTTPClient generic interface
+// Requests are bound to ctx, cancelling it aborts the request in flight.
 type HTTPClient interface {
-	Get(string) (*http.Response, error)
+	Do(ctx context.Context, req *http.Request) (*http.Response, error)
+}
+
+// Get issues a GET request through client
+func Get(ctx context.Context, client HTTPClient, url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(ctx, req)
 }
 
 type googleHTTPClient struct {
-	client *http.Client
 }
 
 // NewGoogleHTTPClient implementation
-func NewGoogleHTTPClient(ctx context.Context) HTTPClient {
-	return &googleHTTPClient{client: urlfetch.Client(ctx)}
+// Delegates to a urlfetch client bound to the context of each request
+func NewGoogleHTTPClient() HTTPClient {
+	return &googleHTTPClient{}
 }
 
-func (c *googleHTTPClient) Get(url string) (*http.Response, error) {
-	return c.client.Get(url)
+func (c *googleHTTPClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	// urlfetch takes its deadline from the context the client was made with
+	return urlfetch.Client(ctx).Do(req.WithContext(ctx))
 }
 
 type goHTTPClient struct {
@@ -35,6 +46,6 @@ func NewGoPClient() HTTPClient {
 	return &goHTTPClient{client: &http.Client{Timeout: 5 * time.Second}}
 }
 
-func (c *goHTTPClient) Get(url string) (*http.Response, error) {
-	return c.client.Get(url)
+func (c *goHTTPClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return c.client.Do(req.WithContext(ctx))
 }
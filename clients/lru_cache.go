@@ -0,0 +1,140 @@
+package clients
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// lruCacheClient is an in-process, size bounded cache
+type lruCacheClient struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	entries    map[string]*list.Element
+	now        func() time.Time
+	codec      Codec
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRUCacheClient new client
+// Evicts the least recently used key once maxEntries is reached
+func NewLRUCacheClient(maxEntries int, codec Codec) CacheClient {
+	return newLRUCacheClient(maxEntries, codec, time.Now)
+}
+
+func newLRUCacheClient(maxEntries int, codec Codec, now func() time.Time) *lruCacheClient {
+	return &lruCacheClient{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		entries:    make(map[string]*list.Element),
+		now:        now,
+		codec:      codec,
+	}
+}
+
+// Codec entries are serialized with
+func (m *lruCacheClient) Codec() Codec {
+	return m.codec
+}
+
+// MultiGet data from cache
+func (m *lruCacheClient) MultiGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if value, ok := m.get(key); ok {
+			result[key] = value
+		}
+	}
+
+	log.Debug(ctx, "cache lookup found", len(result), "of", len(keys))
+	return result, nil
+}
+
+// Get data from cache
+func (m *lruCacheClient) Get(ctx context.Context, key string, result interface{}) error {
+	m.mu.Lock()
+	value, ok := m.get(key)
+	m.mu.Unlock()
+
+	if !ok {
+		log.Debug(ctx, "cache miss", key)
+		return ErrCacheMiss
+	}
+
+	err := FromBytes(m.codec, value, result)
+	if err != nil {
+		log.Error(ctx, "failed to deserialize cached data for key", key, err)
+		return err
+	}
+
+	return nil
+}
+
+// Set data in cache
+func (m *lruCacheClient) Set(ctx context.Context, key string, data interface{}, ttl time.Duration) error {
+	bytes, err := ToBytes(m.codec, data)
+	if err != nil {
+		log.Error(ctx, "failed to serialize cached data for key", key, data, err)
+		return err
+	}
+
+	return m.SetBytes(ctx, key, bytes, ttl)
+}
+
+// SetBytes stores already serialized data
+func (m *lruCacheClient) SetBytes(ctx context.Context, key string, bytes []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = m.now().Add(ttl)
+	}
+
+	if elem, ok := m.entries[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = bytes
+		entry.expiresAt = expiresAt
+		m.ll.MoveToFront(elem)
+		return nil
+	}
+
+	m.entries[key] = m.ll.PushFront(&lruEntry{key: key, value: bytes, expiresAt: expiresAt})
+	for m.maxEntries > 0 && m.ll.Len() > m.maxEntries {
+		m.remove(m.ll.Back())
+	}
+	return nil
+}
+
+// get must be called holding mu
+func (m *lruCacheClient) get(key string) ([]byte, bool) {
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && !m.now().Before(entry.expiresAt) {
+		m.remove(elem)
+		return nil, false
+	}
+
+	m.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+// remove must be called holding mu
+func (m *lruCacheClient) remove(elem *list.Element) {
+	m.ll.Remove(elem)
+	delete(m.entries, elem.Value.(*lruEntry).key)
+}
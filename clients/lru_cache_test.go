@@ -0,0 +1,76 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCacheClient(2, NewJSONCodec())
+
+	cache.Set(ctx, "a", 1, time.Minute)
+	cache.Set(ctx, "b", 2, time.Minute)
+
+	var value int
+	if err := cache.Get(ctx, "a", &value); err != nil || value != 1 {
+		t.Errorf("expected a=1, got: %v, %v", value, err)
+	}
+
+	// b is now the least recently used
+	cache.Set(ctx, "c", 3, time.Minute)
+
+	if err := cache.Get(ctx, "b", &value); err != ErrCacheMiss {
+		t.Errorf("expected b to be evicted, got: %v", err)
+	}
+	results, _ := cache.MultiGet(ctx, []string{"a", "b", "c"})
+	if len(results) != 2 {
+		t.Errorf("expected 2 results, got: %d", len(results))
+	}
+}
+
+func TestLRUCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	cache := newLRUCacheClient(10, NewJSONCodec(), func() time.Time { return now })
+
+	cache.Set(ctx, "a", 1, time.Minute)
+	cache.Set(ctx, "b", 2, 0)
+
+	now = now.Add(2 * time.Minute)
+
+	var value int
+	if err := cache.Get(ctx, "a", &value); err != ErrCacheMiss {
+		t.Errorf("expected a to be expired, got: %v", err)
+	}
+	if err := cache.Get(ctx, "b", &value); err != nil || value != 2 {
+		t.Errorf("expected b=2 to never expire, got: %v, %v", value, err)
+	}
+	if len(cache.entries) != 1 {
+		t.Errorf("expected expired entries to be removed, got: %d", len(cache.entries))
+	}
+}
+
+func TestLRUCacheConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCacheClient(50, NewJSONCodec())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("item:%d", j)
+				cache.Set(ctx, key, i, time.Minute)
+				var value int
+				cache.Get(ctx, key, &value)
+				cache.MultiGet(ctx, []string{key})
+			}
+		}(i)
+	}
+	wg.Wait()
+}
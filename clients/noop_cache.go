@@ -0,0 +1,40 @@
+package clients
+
+import (
+	"context"
+	"time"
+)
+
+type noopCacheClient struct {
+}
+
+// NewNoopCacheClient new client
+// Never stores anything, every lookup is a miss
+func NewNoopCacheClient() CacheClient {
+	return &noopCacheClient{}
+}
+
+// Codec is json, nothing is ever serialized
+func (m *noopCacheClient) Codec() Codec {
+	return NewJSONCodec()
+}
+
+// MultiGet data from cache
+func (m *noopCacheClient) MultiGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	return map[string][]byte{}, nil
+}
+
+// Get data from cache
+func (m *noopCacheClient) Get(ctx context.Context, key string, result interface{}) error {
+	return ErrCacheMiss
+}
+
+// Set data in cache
+func (m *noopCacheClient) Set(ctx context.Context, key string, data interface{}, ttl time.Duration) error {
+	return nil
+}
+
+// SetBytes stores already serialized data
+func (m *noopCacheClient) SetBytes(ctx context.Context, key string, bytes []byte, ttl time.Duration) error {
+	return nil
+}
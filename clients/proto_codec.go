@@ -0,0 +1,52 @@
+package clients
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// ProtoMarshaler is implemented by cache entries that convert to a protobuf message
+// Unmarshal decodes into the message ToProto returns and hands it to FromProto.
+type ProtoMarshaler interface {
+	ToProto() proto.Message
+	FromProto(m proto.Message)
+}
+
+type protoCodec struct{}
+
+// NewProtoCodec encodes entries as protobuf messages, entries must be
+// proto.Messages or ProtoMarshalers
+func NewProtoCodec() Codec {
+	return protoCodec{}
+}
+
+func (protoCodec) ID() byte {
+	return 2
+}
+
+func (protoCodec) Marshal(data interface{}) ([]byte, error) {
+	switch d := data.(type) {
+	case proto.Message:
+		return proto.Marshal(d)
+	case ProtoMarshaler:
+		return proto.Marshal(d.ToProto())
+	}
+	return nil, fmt.Errorf("proto codec can not encode %T", data)
+}
+
+func (protoCodec) Unmarshal(b []byte, result interface{}) error {
+	// unknown fields written by a newer schema are skipped
+	switch r := result.(type) {
+	case proto.Message:
+		return proto.Unmarshal(b, r)
+	case ProtoMarshaler:
+		m := r.ToProto()
+		if err := proto.Unmarshal(b, m); err != nil {
+			return err
+		}
+		r.FromProto(m)
+		return nil
+	}
+	return fmt.Errorf("proto codec can not decode %T", result)
+}
@@ -0,0 +1,149 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrNotRecorded is returned by the replay client for requests missing from its fixtures
+var ErrNotRecorded = errors.New("request was not recorded")
+
+// Recording of an upstream request and its response
+// Recordings are keyed by method, path and query, the host is ignored so
+// fixtures recorded from one mirror replay for any base url.
+type Recording struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+type recordingHTTPClient struct {
+	client HTTPClient
+	dir    string
+}
+
+// NewRecordingHTTPClient writes every response of client to a fixture in dir
+// Failing to write a fixture is logged and does not fail the request.
+func NewRecordingHTTPClient(client HTTPClient, dir string) HTTPClient {
+	return &recordingHTTPClient{client: client, dir: dir}
+}
+
+func (c *recordingHTTPClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	recording := Recording{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   string(body),
+	}
+	path := filepath.Join(c.dir, recordingFile(req))
+	if err := writeRecording(path, recording); err != nil {
+		log.Error(ctx, "failed to record", recording.URL, err)
+	} else {
+		log.Debug(ctx, "recorded", recording.URL, "to", path)
+	}
+
+	return resp, nil
+}
+
+// writeRecording replaces the fixture at path atomically, concurrent
+// recordings of the same request leave one complete fixture behind
+func writeRecording(path string, recording Recording) error {
+	b, err := json.MarshalIndent(recording, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".recording-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+type replayHTTPClient struct {
+	dir string
+}
+
+// NewReplayHTTPClient serves responses recorded by NewRecordingHTTPClient from dir
+// Requests without a fixture fail with ErrNotRecorded, nothing reaches the network.
+func NewReplayHTTPClient(dir string) HTTPClient {
+	return &replayHTTPClient{dir: dir}
+}
+
+func (c *replayHTTPClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(c.dir, recordingFile(req)))
+	if os.IsNotExist(err) {
+		log.Error(ctx, "no recording of", req.Method, req.URL.String())
+		return nil, ErrNotRecorded
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var recording Recording
+	if err := json.Unmarshal(b, &recording); err != nil {
+		return nil, fmt.Errorf("invalid recording of %s: %v", req.URL, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recording.Status, http.StatusText(recording.Status)),
+		StatusCode:    recording.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recording.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(recording.Body))),
+		ContentLength: int64(len(recording.Body)),
+		Request:       req,
+	}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// recordingFile names the fixture of req, readable with a hash to tell apart
+// requests that sanitize to the same name
+func recordingFile(req *http.Request) string {
+	key := req.Method + " " + req.URL.RequestURI()
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	name := unsafeFileChars.ReplaceAllString(req.Method+"_"+req.URL.Path, "_")
+	return fmt.Sprintf("%s_%08x.json", name, h.Sum32())
+}
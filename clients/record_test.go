@@ -0,0 +1,48 @@
+package clients
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestRecordingAndReplayHTTPClient(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "recordings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stub := &stubHTTPClient{statuses: []int{200, 503}}
+	recorder := NewRecordingHTTPClient(stub, dir)
+	for _, url := range []string{"https://hn.example.com/v0/item/1.json", "https://hn.example.com/v0/item/2.json"} {
+		resp, err := Get(ctx, recorder, url)
+		if err != nil {
+			t.Fatalf("expected recorded request to succeed, got: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		if string(body) != "{}" {
+			t.Errorf("expected recorder to pass the body through, got: %q", body)
+		}
+	}
+
+	replay := NewReplayHTTPClient(dir)
+	resp, err := Get(ctx, replay, "https://mirror.example.com/v0/item/2.json")
+	if err != nil {
+		t.Fatalf("expected recording to replay for another host, got: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 503 || string(body) != "{}" {
+		t.Errorf("expected recorded response, got %d %q", resp.StatusCode, body)
+	}
+
+	_, err = Get(ctx, replay, "https://hn.example.com/v0/item/3.json")
+	if err != ErrNotRecorded {
+		t.Errorf("expected ErrNotRecorded, got: %v", err)
+	}
+	if stub.calls != 2 {
+		t.Errorf("expected replay to stay off the network, got %d calls", stub.calls)
+	}
+}
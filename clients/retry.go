@@ -0,0 +1,171 @@
+package clients
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy configures retries of failed http requests
+type RetryPolicy struct {
+	// MaxAttempts including the first request
+	MaxAttempts int
+	// BaseDelay doubles on every retry, up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter randomly shortens each delay by up to this fraction
+	Jitter float64
+}
+
+// DefaultRetryPolicy retries twice, within a few hundred milliseconds
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+	Jitter:      0.5,
+}
+
+// RetryCounter counts retries across clients
+type RetryCounter struct {
+	attempts  uint64
+	retries   uint64
+	exhausted uint64
+}
+
+// RetryStats snapshot of a RetryCounter
+type RetryStats struct {
+	Attempts  uint64 `json:"attempts"`
+	Retries   uint64 `json:"retries"`
+	Exhausted uint64 `json:"exhausted"`
+}
+
+// Stats returns the current counts
+func (c *RetryCounter) Stats() RetryStats {
+	return RetryStats{
+		Attempts:  atomic.LoadUint64(&c.attempts),
+		Retries:   atomic.LoadUint64(&c.retries),
+		Exhausted: atomic.LoadUint64(&c.exhausted),
+	}
+}
+
+var jitterRand = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+type retryingHTTPClient struct {
+	client  HTTPClient
+	policy  RetryPolicy
+	counter *RetryCounter
+}
+
+// NewRetryingHTTPClient retries timeouts, 5xx and 429 responses of client
+// Retry-After is honoured up to the MaxDelay of policy. Gives up early rather than wait past the deadline of the request context.
+// Only requests without a body can be retried.
+func NewRetryingHTTPClient(client HTTPClient, policy RetryPolicy, counter *RetryCounter) HTTPClient {
+	return &retryingHTTPClient{client: client, policy: policy, counter: counter}
+}
+
+func (c *retryingHTTPClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	for attempt := 0; ; attempt++ {
+		atomic.AddUint64(&c.counter.attempts, 1)
+		resp, err := c.client.Do(ctx, req)
+		if req.Body != nil || !isRetryable(ctx, resp, err) {
+			return resp, err
+		}
+
+		delay := c.policy.delay(attempt, resp)
+		if attempt+1 >= c.policy.MaxAttempts || !canWait(ctx, delay) {
+			log.Error(ctx, "giving up on", url, "after", attempt+1, "attempts")
+			atomic.AddUint64(&c.counter.exhausted, 1)
+			return resp, err
+		}
+
+		if resp != nil {
+			drain(resp.Body)
+		}
+		log.Info(ctx, "retrying", url, "in", delay, "after", err)
+		atomic.AddUint64(&c.counter.retries, 1)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// canWait reports whether there is time left to retry after delay
+func canWait(ctx context.Context, delay time.Duration) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Now().Add(delay).Before(deadline)
+}
+
+func (p RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	// honour Retry-After, but never wait longer than MaxDelay
+	if retryAfter, ok := parseRetryAfter(resp); ok {
+		if retryAfter > p.MaxDelay {
+			return p.MaxDelay
+		}
+		return retryAfter
+	}
+
+	delay := p.BaseDelay << uint(attempt)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+
+	jitterRand.Lock()
+	jitter := jitterRand.Float64() * p.Jitter
+	jitterRand.Unlock()
+	return delay - time.Duration(float64(delay)*jitter)
+}
+
+func isRetryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		netErr, ok := err.(net.Error)
+		return ok && netErr.Timeout()
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// parseRetryAfter supports both the seconds and http date forms
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+func drain(body io.ReadCloser) {
+	io.Copy(ioutil.Discard, body)
+	body.Close()
+}
@@ -0,0 +1,120 @@
+package clients
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubHTTPClient struct {
+	statuses []int
+	headers  []http.Header
+	calls    int
+}
+
+func (c *stubHTTPClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	i := c.calls
+	if i >= len(c.statuses) {
+		i = len(c.statuses) - 1
+	}
+	c.calls++
+
+	header := http.Header{}
+	if i < len(c.headers) {
+		header = c.headers[i]
+	}
+	return &http.Response{
+		StatusCode: c.statuses[i],
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader("{}")),
+	}, nil
+}
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, Jitter: 0.5}
+
+func TestRetryingHTTPClientRetriesServerErrors(t *testing.T) {
+	stub := &stubHTTPClient{statuses: []int{503, 429, 200}}
+	counter := &RetryCounter{}
+	client := NewRetryingHTTPClient(stub, testRetryPolicy, counter)
+
+	resp, err := Get(context.Background(), client, "http://hn")
+	if err != nil || resp.StatusCode != 200 {
+		t.Errorf("expected 200 after retries, got: %v, %v", resp, err)
+	}
+
+	expected := RetryStats{Attempts: 3, Retries: 2, Exhausted: 0}
+	if counter.Stats() != expected {
+		t.Errorf("unexpected stats, got: %v, want: %v.", counter.Stats(), expected)
+	}
+}
+
+func TestRetryingHTTPClientGivesUp(t *testing.T) {
+	stub := &stubHTTPClient{statuses: []int{500}}
+	counter := &RetryCounter{}
+	client := NewRetryingHTTPClient(stub, testRetryPolicy, counter)
+
+	resp, _ := Get(context.Background(), client, "http://hn")
+	if resp.StatusCode != 500 || stub.calls != 3 || counter.Stats().Exhausted != 1 {
+		t.Errorf("expected to give up after 3 attempts, got: %d after %d calls", resp.StatusCode, stub.calls)
+	}
+}
+
+func TestRetryingHTTPClientSkipsClientErrors(t *testing.T) {
+	stub := &stubHTTPClient{statuses: []int{404}}
+	client := NewRetryingHTTPClient(stub, testRetryPolicy, &RetryCounter{})
+
+	Get(context.Background(), client, "http://hn")
+	if stub.calls != 1 {
+		t.Errorf("expected no retries, got: %d calls", stub.calls)
+	}
+}
+
+func TestRetryingHTTPClientRespectsDeadline(t *testing.T) {
+	stub := &stubHTTPClient{
+		statuses: []int{429, 200},
+		headers:  []http.Header{{"Retry-After": []string{"120"}}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	policy := testRetryPolicy
+	policy.MaxDelay = time.Hour
+	client := NewRetryingHTTPClient(stub, policy, &RetryCounter{})
+
+	resp, _ := Get(ctx, client, "http://hn")
+	if resp.StatusCode != 429 || stub.calls != 1 {
+		t.Errorf("expected Retry-After past the deadline to give up, got: %d after %d calls", resp.StatusCode, stub.calls)
+	}
+}
+
+func TestRetryingHTTPClientCapsRetryAfter(t *testing.T) {
+	stub := &stubHTTPClient{
+		statuses: []int{503, 200},
+		headers:  []http.Header{{"Retry-After": []string{"3600"}}},
+	}
+	client := NewRetryingHTTPClient(stub, testRetryPolicy, &RetryCounter{})
+
+	start := time.Now()
+	resp, _ := Get(context.Background(), client, "http://hn")
+	if resp.StatusCode != 200 || stub.calls != 2 {
+		t.Errorf("expected a retry after MaxDelay, got: %d after %d calls", resp.StatusCode, stub.calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected Retry-After to be capped by MaxDelay, waited %v", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	if delay, ok := parseRetryAfter(resp); !ok || delay != 2*time.Second {
+		t.Errorf("expected 2s, got: %v", delay)
+	}
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	resp = &http.Response{Header: http.Header{"Retry-After": []string{date}}}
+	if delay, ok := parseRetryAfter(resp); !ok || delay <= 0 || delay > time.Minute {
+		t.Errorf("expected about a minute, got: %v", delay)
+	}
+}
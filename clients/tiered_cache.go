@@ -0,0 +1,145 @@
+package clients
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// backfillTTL caps how long L2 hits live in L1, the ttl L2 entries have left is unknown
+const backfillTTL = 10 * time.Second
+
+// TieredCacheClient layers an in-process LRU (L1) over a remote cache (L2)
+// Reads go through L1 then L2, backfilling L1 on L2 hits. Writes go to both.
+type TieredCacheClient struct {
+	// counters first, keeps them 64-bit aligned for atomic
+	localHits    uint64
+	localMisses  uint64
+	remoteHits   uint64
+	remoteMisses uint64
+
+	local     *lruCacheClient
+	remote    CacheClient
+	localTTL  time.Duration
+	remoteTTL time.Duration
+	codec     Codec
+}
+
+// TieredCacheStats hit and miss counters per tier
+type TieredCacheStats struct {
+	LocalHits    uint64 `json:"localHits"`
+	LocalMisses  uint64 `json:"localMisses"`
+	RemoteHits   uint64 `json:"remoteHits"`
+	RemoteMisses uint64 `json:"remoteMisses"`
+}
+
+// NewTieredCacheClient new client
+// localTTL caps how long entries live in L1, remoteTTL overrides the ttl of L2 entries when non zero.
+// L1 shares the codec of remote, so L2 hits are backfilled without re-encoding.
+func NewTieredCacheClient(remote CacheClient, localMaxEntries int, localTTL time.Duration, remoteTTL time.Duration) *TieredCacheClient {
+	return &TieredCacheClient{
+		local:     newLRUCacheClient(localMaxEntries, remote.Codec(), time.Now),
+		remote:    remote,
+		localTTL:  localTTL,
+		remoteTTL: remoteTTL,
+		codec:     remote.Codec(),
+	}
+}
+
+// Codec entries are serialized with, the codec of the remote cache
+func (m *TieredCacheClient) Codec() Codec {
+	return m.codec
+}
+
+// MultiGet data from cache
+func (m *TieredCacheClient) MultiGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result, _ := m.local.MultiGet(ctx, keys)
+	atomic.AddUint64(&m.localHits, uint64(len(result)))
+
+	missingKeys := make([]string, 0, len(keys)-len(result))
+	for _, key := range keys {
+		if _, ok := result[key]; !ok {
+			missingKeys = append(missingKeys, key)
+		}
+	}
+	atomic.AddUint64(&m.localMisses, uint64(len(missingKeys)))
+	if len(missingKeys) == 0 {
+		return result, nil
+	}
+
+	remoteResult, err := m.remote.MultiGet(ctx, missingKeys)
+	if err != nil {
+		// degrade to whatever L1 had, callers treat the rest as misses
+		log.Error(ctx, "failed fetching from remote cache", len(missingKeys), err)
+		atomic.AddUint64(&m.remoteMisses, uint64(len(missingKeys)))
+		return result, nil
+	}
+	atomic.AddUint64(&m.remoteHits, uint64(len(remoteResult)))
+	atomic.AddUint64(&m.remoteMisses, uint64(len(missingKeys)-len(remoteResult)))
+
+	ttl := backfillTTL
+	if m.localTTL > 0 && m.localTTL < ttl {
+		ttl = m.localTTL
+	}
+	for key, value := range remoteResult {
+		m.local.SetBytes(ctx, key, value, ttl)
+		result[key] = value
+	}
+	return result, nil
+}
+
+// Get data from cache
+func (m *TieredCacheClient) Get(ctx context.Context, key string, result interface{}) error {
+	values, err := m.MultiGet(ctx, []string{key})
+	if err != nil {
+		return err
+	}
+
+	value, ok := values[key]
+	if !ok {
+		log.Debug(ctx, "cache miss", key)
+		return ErrCacheMiss
+	}
+
+	err = FromBytes(m.codec, value, result)
+	if err != nil {
+		log.Error(ctx, "failed to deserialize cached data for key", key, err)
+		return err
+	}
+	return nil
+}
+
+// Set data in cache
+func (m *TieredCacheClient) Set(ctx context.Context, key string, data interface{}, ttl time.Duration) error {
+	bytes, err := ToBytes(m.codec, data)
+	if err != nil {
+		log.Error(ctx, "failed to serialize cached data for key", key, data, err)
+		return err
+	}
+	return m.SetBytes(ctx, key, bytes, ttl)
+}
+
+// SetBytes stores already serialized data in both tiers
+func (m *TieredCacheClient) SetBytes(ctx context.Context, key string, bytes []byte, ttl time.Duration) error {
+	localTTL := ttl
+	if m.localTTL > 0 && (localTTL <= 0 || m.localTTL < localTTL) {
+		localTTL = m.localTTL
+	}
+	m.local.SetBytes(ctx, key, bytes, localTTL)
+
+	remoteTTL := ttl
+	if m.remoteTTL > 0 {
+		remoteTTL = m.remoteTTL
+	}
+	return m.remote.SetBytes(ctx, key, bytes, remoteTTL)
+}
+
+// Stats returns the hit and miss counters of each tier
+func (m *TieredCacheClient) Stats() TieredCacheStats {
+	return TieredCacheStats{
+		LocalHits:    atomic.LoadUint64(&m.localHits),
+		LocalMisses:  atomic.LoadUint64(&m.localMisses),
+		RemoteHits:   atomic.LoadUint64(&m.remoteHits),
+		RemoteMisses: atomic.LoadUint64(&m.remoteMisses),
+	}
+}
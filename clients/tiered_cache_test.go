@@ -0,0 +1,120 @@
+package clients
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTieredCacheReadThrough(t *testing.T) {
+	ctx := context.Background()
+	remote := NewLRUCacheClient(10, NewJSONCodec())
+	remote.Set(ctx, "a", 1, time.Minute)
+
+	cache := NewTieredCacheClient(remote, 10, time.Minute, 0)
+
+	var value int
+	if err := cache.Get(ctx, "a", &value); err != nil || value != 1 {
+		t.Errorf("expected a=1, got: %v, %v", value, err)
+	}
+	if err := cache.Get(ctx, "a", &value); err != nil || value != 1 {
+		t.Errorf("expected a=1, got: %v, %v", value, err)
+	}
+	if err := cache.Get(ctx, "b", &value); err != ErrCacheMiss {
+		t.Errorf("expected b to miss, got: %v", err)
+	}
+
+	expected := TieredCacheStats{LocalHits: 1, LocalMisses: 2, RemoteHits: 1, RemoteMisses: 1}
+	if !cmp.Equal(expected, cache.Stats()) {
+		t.Errorf("unexpected stats, got: %v, want: %v.", cache.Stats(), expected)
+	}
+}
+
+func TestTieredCacheWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	remote := NewLRUCacheClient(10, NewJSONCodec())
+	cache := NewTieredCacheClient(remote, 10, time.Minute, 0)
+
+	cache.Set(ctx, "a", 1, time.Hour)
+
+	var value int
+	if err := remote.Get(ctx, "a", &value); err != nil || value != 1 {
+		t.Errorf("expected remote a=1, got: %v, %v", value, err)
+	}
+
+	results, _ := cache.MultiGet(ctx, []string{"a", "b"})
+	if len(results) != 1 {
+		t.Errorf("expected 1 result, got: %d", len(results))
+	}
+	if cache.Stats().RemoteHits != 0 {
+		t.Errorf("expected a to be served locally, got: %v", cache.Stats())
+	}
+}
+
+func TestTieredCacheSharesRemoteCodec(t *testing.T) {
+	ctx := context.Background()
+	remote := NewLRUCacheClient(10, NewMsgpackCodec())
+	cache := NewTieredCacheClient(remote, 10, time.Minute, 0)
+	jsonCache := NewLRUCacheClient(10, NewJSONCodec())
+	cache.Set(ctx, "a", 1, time.Minute)
+	jsonCache.Set(ctx, "a", 2, time.Minute)
+
+	var value int
+	if err := cache.Get(ctx, "a", &value); err != nil || value != 1 {
+		t.Errorf("expected a=1 from L1, got: %v, %v", value, err)
+	}
+	if err := remote.Get(ctx, "a", &value); err != nil || value != 1 {
+		t.Errorf("expected a=1 from L2, got: %v, %v", value, err)
+	}
+	if err := jsonCache.Get(ctx, "a", &value); err != nil || value != 2 {
+		t.Errorf("expected a=2 from the json cache, got: %v, %v", value, err)
+	}
+	if cache.Codec().ID() != NewMsgpackCodec().ID() {
+		t.Errorf("expected tiered cache to use the remote codec, got: %d", cache.Codec().ID())
+	}
+}
+
+func TestTieredCacheCapsBackfillTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	remote := NewLRUCacheClient(10, NewJSONCodec())
+	remote.Set(ctx, "a", 1, time.Hour)
+	cache := NewTieredCacheClient(remote, 10, time.Minute, 0)
+	cache.local.now = func() time.Time { return now }
+
+	cache.MultiGet(ctx, []string{"a"})
+	cache.MultiGet(ctx, []string{"a"})
+	if cache.Stats().RemoteHits != 1 {
+		t.Errorf("expected a to be backfilled, got: %v", cache.Stats())
+	}
+
+	now = now.Add(backfillTTL)
+	cache.MultiGet(ctx, []string{"a"})
+	if cache.Stats().RemoteHits != 2 {
+		t.Errorf("expected backfilled a to expire after %v, got: %v", backfillTTL, cache.Stats())
+	}
+}
+
+// countingCodec counts the values it encodes
+type countingCodec struct {
+	Codec
+	marshals int
+}
+
+func (c *countingCodec) Marshal(data interface{}) ([]byte, error) {
+	c.marshals++
+	return c.Codec.Marshal(data)
+}
+
+func TestTieredCacheEncodesOnce(t *testing.T) {
+	ctx := context.Background()
+	codec := &countingCodec{Codec: NewJSONCodec()}
+	cache := NewTieredCacheClient(NewLRUCacheClient(10, codec), 10, time.Minute, 0)
+
+	cache.Set(ctx, "a", 1, time.Hour)
+	if codec.marshals != 1 {
+		t.Errorf("expected a to be encoded once, got: %d", codec.marshals)
+	}
+}
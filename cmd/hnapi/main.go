@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cevaris/hnapi/backend"
+	"github.com/cevaris/hnapi/clients"
+	"github.com/cevaris/hnapi/server"
+	"github.com/cevaris/timber"
+)
+
+var log = timber.NewGoogleLogger()
+
+// config flags, addr, upstream, cache, cache-codec, memcache, record and replay default to their HNAPI_* environment variables
+var (
+	addr            = flag.String("addr", envOr("HNAPI_ADDR", ":8080"), "address to listen on")
+	upstream        = flag.String("upstream", envOr("HNAPI_UPSTREAM", backend.DefaultBaseURL), "comma separated HN API base urls, mirrors in fallback order")
+	cache           = flag.String("cache", envOr("HNAPI_CACHE", "none"), "cache backend, one of none|local|memcache|tiered")
+	cacheCodec      = flag.String("cache-codec", envOr("HNAPI_CACHE_CODEC", "json"), "cache entry serialization, one of json|proto|msgpack")
+	cacheSize       = flag.Int("cache-size", 10000, "max entries held by the local cache")
+	maxRequests     = flag.Int("max-requests", backend.DefaultMaxRequests, "max concurrent requests to the HN API")
+	maxBulkRequests = flag.Int("max-bulk-requests", backend.DefaultMaxBulkRequests, "max concurrent requests to the HN API used by feeds")
+	localTTL        = flag.Duration("local-ttl", time.Minute, "max ttl of entries in the local tier of the tiered cache")
+	memcacheHost    = flag.String("memcache", envOr("HNAPI_MEMCACHE", "localhost:11211"), "memcache host:port")
+	recordDir       = flag.String("record", envOr("HNAPI_RECORD", ""), "directory to record HN API responses to")
+	replayDir       = flag.String("replay", envOr("HNAPI_REPLAY", ""), "directory to replay recorded HN API responses from, instead of the network")
+)
+
+func envOr(key string, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+func newCacheClient(name string, codec clients.Codec) (clients.CacheClient, error) {
+	switch name {
+	case "none":
+		return clients.NewNoopCacheClient(), nil
+	case "local":
+		return clients.NewLRUCacheClient(*cacheSize, codec), nil
+	case "memcache":
+		return clients.NewBradfitzMemcacheClient(*memcacheHost, codec), nil
+	case "tiered":
+		remote := clients.NewBradfitzMemcacheClient(*memcacheHost, codec)
+		return clients.NewTieredCacheClient(remote, *cacheSize, *localTTL, 0), nil
+	default:
+		return nil, fmt.Errorf("unknown cache '%s'", name)
+	}
+}
+
+func newHTTPClient() (clients.HTTPClient, error) {
+	switch {
+	case *recordDir != "" && *replayDir != "":
+		return nil, fmt.Errorf("-record and -replay are mutually exclusive")
+	case *recordDir != "":
+		return clients.NewRecordingHTTPClient(clients.NewGoPClient(), *recordDir), nil
+	case *replayDir != "":
+		return clients.NewReplayHTTPClient(*replayDir), nil
+	default:
+		return clients.NewGoPClient(), nil
+	}
+}
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+
+	codec, err := clients.NewCodec(*cacheCodec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	cacheClient, err := newCacheClient(*cache, codec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	httpClient, err := newHTTPClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	s := &server.Server{
+		NewContext:      func(r *http.Request) context.Context { return r.Context() },
+		HTTPClient:      httpClient,
+		BaseURLs:        strings.Split(*upstream, ","),
+		NewCacheClient:  func(context.Context) clients.CacheClient { return cacheClient },
+		MaxRequests:     *maxRequests,
+		MaxBulkRequests: *maxBulkRequests,
+	}
+
+	srv := &http.Server{
+		Addr:         *addr,
+		Handler:      s.Router(),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+	log.Info(ctx, "listening on", *addr, "with cache", *cache)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Error(ctx, "server stopped", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,198 @@
+package hntest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cevaris/hnapi/model"
+)
+
+// Fixtures served by a fake HN API
+type Fixtures struct {
+	Items   map[int]model.Item    `json:"items"`
+	Users   map[string]model.User `json:"users"`
+	Feeds   map[string][]int      `json:"feeds"`
+	MaxItem int                   `json:"maxitem"`
+	Updates Updates               `json:"updates"`
+}
+
+// Updates is the body of /v0/updates.json
+type Updates struct {
+	Items    []int    `json:"items"`
+	Profiles []string `json:"profiles"`
+}
+
+// LoadFixtures reads fixtures from a json file
+func LoadFixtures(path string) (Fixtures, error) {
+	var fixtures Fixtures
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fixtures, err
+	}
+	err = json.Unmarshal(b, &fixtures)
+	return fixtures, err
+}
+
+// ThreadFixtures is the path of the bundled fixtures, a small thread under story 1
+func ThreadFixtures() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata", "thread.json")
+}
+
+// NewThreadServer starts a fake HN API serving ThreadFixtures, callers must Close it
+func NewThreadServer(t testing.TB) *Server {
+	fixtures, err := LoadFixtures(ThreadFixtures())
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	return NewServer(fixtures)
+}
+
+// Server is an in-process fake of the HN API
+// Serves /v0/item/<id>.json, /v0/user/<id>.json, /v0/<feed>.json,
+// /v0/maxitem.json and /v0/updates.json from its fixtures.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	fixtures Fixtures
+	latency  time.Duration
+	statuses map[string]int
+	nulls    map[string]bool
+	requests map[string]int
+}
+
+// NewServer starts a fake HN API, callers must Close it
+func NewServer(fixtures Fixtures) *Server {
+	s := &Server{
+		fixtures: fixtures,
+		statuses: make(map[string]int),
+		nulls:    make(map[string]bool),
+		requests: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// BaseURL to configure backends with
+func (s *Server) BaseURL() string {
+	return s.URL + "/v0"
+}
+
+// ItemPath of an item
+func ItemPath(itemID int) string {
+	return fmt.Sprintf("/v0/item/%d.json", itemID)
+}
+
+// UserPath of a user
+func UserPath(userID string) string {
+	return fmt.Sprintf("/v0/user/%s.json", userID)
+}
+
+// SetLatency delays every response
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// SetStatus responds to path with an html error page of status
+func (s *Server) SetStatus(path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[path] = status
+}
+
+// SetNull responds to path with null, like firebase does for unknown resources
+func (s *Server) SetNull(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nulls[path] = true
+}
+
+// SetItem adds or replaces an item fixture
+func (s *Server) SetItem(item model.Item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fixtures.Items == nil {
+		s.fixtures.Items = make(map[int]model.Item)
+	}
+	s.fixtures.Items[item.ID] = item
+}
+
+// Requests made to path so far
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	latency := s.latency
+	status, hasStatus := s.statuses[r.URL.Path]
+	isNull := s.nulls[r.URL.Path]
+	body, found := s.lookup(r.URL.Path)
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if hasStatus {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "<html><body>%d</body></html>", status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if isNull || !found {
+		w.Write([]byte("null"))
+		return
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+// lookup the fixture of path, must be called holding mu
+func (s *Server) lookup(path string) (interface{}, bool) {
+	if !strings.HasPrefix(path, "/v0/") || !strings.HasSuffix(path, ".json") {
+		return nil, false
+	}
+	resource := strings.TrimSuffix(strings.TrimPrefix(path, "/v0/"), ".json")
+
+	switch {
+	case strings.HasPrefix(resource, "item/"):
+		itemID, err := strconv.Atoi(strings.TrimPrefix(resource, "item/"))
+		if err != nil {
+			return nil, false
+		}
+		item, ok := s.fixtures.Items[itemID]
+		return item, ok
+	case strings.HasPrefix(resource, "user/"):
+		user, ok := s.fixtures.Users[strings.TrimPrefix(resource, "user/")]
+		return user, ok
+	case resource == "maxitem":
+		return s.fixtures.MaxItem, true
+	case resource == "updates":
+		return s.fixtures.Updates, true
+	}
+
+	itemIds, ok := s.fixtures.Feeds[resource]
+	return itemIds, ok
+}
@@ -5,6 +5,9 @@ type Items struct {
 	Items        []Item       `json:"items"`
 	Conversation Conversation `json:"conversation,omitempty"`
 	Comments     []Item       `json:"comments,omitempty"`
+	Next         int          `json:"next,omitempty"`
+	Partial      bool         `json:"partial,omitempty"`
+	Failures     []Failure    `json:"failures,omitempty"`
 }
 
 // Item is either Story, Comment, or Poll
@@ -29,12 +32,49 @@ type Item struct {
 	URL   string `json:"url,omitempty"`
 	Score int    `json:"score,omitempty"`
 	Title string `json:"title,omitempty"`
+
+	// Missing is set on placeholders of items that could not be hydrated
+	Missing string `json:"missing,omitempty"`
+}
+
+// Reasons an item is Missing
+const (
+	MissingNotFound = "not_found"
+	MissingError    = "error"
+)
+
+// Failure describes an item that could not be hydrated
+type Failure struct {
+	ID      int    `json:"id"`
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// NewMissingItem placeholder constructor
+func NewMissingItem(ID int, reason string) Item {
+	return Item{ID: ID, Missing: reason}
 }
 
 // Conversation assit rendering nested comments
+// UnloadedIds are the kids of a truncated node that were not hydrated, to lazy load them by.
+// UnloadedDescendants counts all comments under the node that were not hydrated, HN only
+// reports the descendants of stories and polls, so it is only set on their node.
 type Conversation struct {
 	ID   int             `json:"id"`
 	Kids []*Conversation `json:"kids"`
+
+	UnloadedDescendants int   `json:"unloadedDescendants,omitempty"`
+	UnloadedIds         []int `json:"unloadedIds,omitempty"`
+
+	// Item, Depth and Replies are only set in the tree shape
+	Item    *Item `json:"item,omitempty"`
+	Depth   int   `json:"depth,omitempty"`
+	Replies int   `json:"replies,omitempty"`
+}
+
+// Truncate marks kids of the node as unloaded
+func (c *Conversation) Truncate(kids []int) {
+	c.UnloadedIds = append(c.UnloadedIds, kids...)
 }
 
 // NewConversation constructor
@@ -0,0 +1,19 @@
+package model
+
+// Users is for serializin json
+type Users struct {
+	Users       []User    `json:"users"`
+	Submissions []Item    `json:"submissions,omitempty"`
+	Partial     bool      `json:"partial,omitempty"`
+	Failures    []Failure `json:"failures,omitempty"`
+}
+
+// User is a HN account
+type User struct {
+	ID      string `json:"id"`
+	Created int    `json:"created,omitempty"`
+	Karma   int    `json:"karma,omitempty"`
+	About   string `json:"about,omitempty"`
+
+	Submitted []int `json:"submitted,omitempty"`
+}
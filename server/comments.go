@@ -0,0 +1,417 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cevaris/hnapi/api"
+	"github.com/cevaris/hnapi/backend"
+	"github.com/cevaris/hnapi/model"
+)
+
+// commentBatchSize bounds the item ids of a single itemRepo.Get
+const commentBatchSize = 100
+
+// commentParallelism bounds the concurrent itemRepo.Get calls of a comment tree
+const commentParallelism = 4
+
+// commentLimits bound the comment tree of an item, zero values are unlimited
+type commentLimits struct {
+	// depth is the number of levels of comments to hydrate
+	depth int
+	// maxComments is the total number of comments to hydrate
+	maxComments int
+	// kidsLimit is the number of kids to hydrate per comment
+	kidsLimit int
+	// displayPrefix is the number of comments to hydrate in display order,
+	// comments that can only be displayed after them are not fetched
+	displayPrefix int
+}
+
+// getCommentLimits parses the depth, maxComments and kidsLimit query params
+func getCommentLimits(ctx context.Context, r *http.Request) (commentLimits, error) {
+	var limits commentLimits
+	params := []struct {
+		name  string
+		value *int
+	}{
+		{"depth", &limits.depth},
+		{"maxComments", &limits.maxComments},
+		{"kidsLimit", &limits.kidsLimit},
+	}
+	for _, param := range params {
+		value, err := api.GetQueryInt(ctx, r, param.name, 0)
+		if err != nil {
+			return limits, err
+		}
+		if value < 0 {
+			return limits, fmt.Errorf("invalid '%s' param %d, expected a positive value", param.name, value)
+		}
+		*param.value = value
+	}
+	return limits, nil
+}
+
+// Response shapes of comments
+const (
+	// shapeFlat returns comments as a flat list next to an id only conversation tree
+	shapeFlat = "flat"
+	// shapeTree embeds comments into the conversation tree
+	shapeTree = "tree"
+)
+
+// getShape parses the 'shape' query param
+func getShape(r *http.Request) (string, error) {
+	shape := r.URL.Query().Get("shape")
+	switch shape {
+	case "":
+		return shapeFlat, nil
+	case shapeFlat, shapeTree:
+		return shape, nil
+	default:
+		return "", fmt.Errorf("invalid 'shape' param '%s', expected one of %s|%s", shape, shapeFlat, shapeTree)
+	}
+}
+
+// embedComments embeds items into the nodes of conversation, along with
+// their depth and number of loaded replies
+func embedComments(conversation *model.Conversation, items []model.Item) {
+	itemsByID := make(map[int]model.Item, len(items))
+	for _, item := range items {
+		itemsByID[item.ID] = item
+	}
+
+	var embed func(node *model.Conversation, depth int) int
+	embed = func(node *model.Conversation, depth int) int {
+		if item, ok := itemsByID[node.ID]; ok {
+			node.Item = &item
+		}
+		node.Depth = depth
+		node.Replies = 0
+		for _, kid := range node.Kids {
+			node.Replies += 1 + embed(kid, depth+1)
+		}
+		return node.Replies
+	}
+	embed(conversation, 0)
+}
+
+// Sort orders of comments
+const (
+	// sortHN keeps the upstream order of kids
+	sortHN          = "hn"
+	sortOldest      = "oldest"
+	sortNewest      = "newest"
+	sortMostReplies = "most-replies"
+	sortAuthor      = "author"
+)
+
+// getSort parses the 'sort' query param, empty if not set
+func getSort(r *http.Request) (string, error) {
+	order := r.URL.Query().Get("sort")
+	switch order {
+	case "", sortHN, sortOldest, sortNewest, sortMostReplies, sortAuthor:
+		return order, nil
+	default:
+		return "", fmt.Errorf("invalid 'sort' param '%s', expected one of %s|%s|%s|%s|%s", order, sortHN, sortOldest, sortNewest, sortMostReplies, sortAuthor)
+	}
+}
+
+// sortConversation sorts every level of conversation by order, returning
+// comments sorted by the same order across all levels
+// Ties keep the display order of the sorted tree, replies count loaded descendants only.
+func sortConversation(conversation *model.Conversation, comments []model.Item, order string) []model.Item {
+	itemsByID := make(map[int]model.Item, len(comments))
+	for _, item := range comments {
+		itemsByID[item.ID] = item
+	}
+
+	replies := make(map[int]int)
+	var count func(node *model.Conversation) int
+	count = func(node *model.Conversation) int {
+		for _, kid := range node.Kids {
+			replies[node.ID] += 1 + count(kid)
+		}
+		return replies[node.ID]
+	}
+	count(conversation)
+
+	less := func(a int, b int) bool {
+		switch order {
+		case sortOldest:
+			return itemsByID[a].Time < itemsByID[b].Time
+		case sortNewest:
+			return itemsByID[a].Time > itemsByID[b].Time
+		case sortMostReplies:
+			return replies[a] > replies[b]
+		case sortAuthor:
+			return strings.ToLower(itemsByID[a].By) < strings.ToLower(itemsByID[b].By)
+		}
+		return false
+	}
+
+	sorted := make([]model.Item, 0, len(comments))
+	var walk func(node *model.Conversation)
+	walk = func(node *model.Conversation) {
+		kids := node.Kids
+		sort.SliceStable(kids, func(i, j int) bool { return less(kids[i].ID, kids[j].ID) })
+		for _, kid := range kids {
+			if item, ok := itemsByID[kid.ID]; ok {
+				sorted = append(sorted, item)
+			}
+			walk(kid)
+		}
+	}
+	walk(conversation)
+
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i].ID, sorted[j].ID) })
+	return sorted
+}
+
+// commentsError aggregates the errors of comment batches that failed as a whole
+type commentsError struct {
+	errs []error
+}
+
+func (e *commentsError) Error() string {
+	messages := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("failed hydrating %d comment batches: %s", len(e.errs), strings.Join(messages, "; "))
+}
+
+// HTTPStatus of the first failed batch
+func (e *commentsError) HTTPStatus() int {
+	if statusErr, ok := e.errs[0].(api.StatusError); ok {
+		return statusErr.HTTPStatus()
+	}
+	return http.StatusBadGateway
+}
+
+// pendingKids are the kids of a conversation node yet to be hydrated
+type pendingKids struct {
+	node *model.Conversation
+	kids []int
+}
+
+// hydrateComments hydrates the comment tree under conversation breadth first
+// Each level is fetched with batched itemRepo.Get calls across all siblings.
+// Items that failed to hydrate are collected into failures, whole batches
+// that failed are collected into the returned error as well.
+// Kids beyond limits are left unloaded and recorded on their parent node.
+func hydrateComments(ctx context.Context, itemRepo backend.ItemRepo, commentIds []int, limits commentLimits, results *[]model.Item, conversation *model.Conversation, failures *[]model.Failure) error {
+	seen := make(map[int]bool)
+	level := []pendingKids{{node: conversation, kids: commentIds}}
+	budget := limits.maxComments
+	var errs []error
+
+	for depth := 1; len(level) > 0; depth++ {
+		if limits.displayPrefix > 0 {
+			pruneToDisplayPrefix(conversation, level, limits.displayPrefix)
+		}
+
+		levelIds := make([]int, 0)
+		for i, pending := range level {
+			kids := pending.kids
+			if limits.kidsLimit > 0 && len(kids) > limits.kidsLimit {
+				pending.node.Truncate(kids[limits.kidsLimit:])
+				kids = kids[:limits.kidsLimit]
+			}
+			for j, ID := range kids {
+				if limits.maxComments > 0 && budget == 0 {
+					pending.node.Truncate(kids[j:])
+					kids = kids[:j]
+					break
+				}
+				if !seen[ID] {
+					seen[ID] = true
+					levelIds = append(levelIds, ID)
+					budget--
+				}
+			}
+			level[i].kids = kids
+		}
+
+		items, levelFailures, levelErrs := getComments(ctx, itemRepo, levelIds)
+		*failures = append(*failures, levelFailures...)
+		errs = append(errs, levelErrs...)
+
+		// attach nodes in the order of their parent's kids
+		next := make([]pendingKids, 0)
+		for _, pending := range level {
+			for _, ID := range pending.kids {
+				item, ok := items[ID]
+				if !ok {
+					continue
+				}
+				delete(items, ID)
+
+				*results = append(*results, item)
+				node := model.NewConversation(ID)
+				pending.node.Kids = append(pending.node.Kids, node)
+				if len(item.Kids) == 0 {
+					continue
+				}
+				if limits.depth > 0 && depth >= limits.depth {
+					node.Truncate(item.Kids)
+					continue
+				}
+				next = append(next, pendingKids{node: node, kids: item.Kids})
+			}
+		}
+		level = next
+	}
+
+	if len(errs) > 0 {
+		return &commentsError{errs: errs}
+	}
+	return nil
+}
+
+// unloadedDescendants of item once comments were hydrated under it,
+// failed comments count as unloaded
+func unloadedDescendants(item model.Item, comments []model.Item) int {
+	unloaded := item.Decendants
+	for _, comment := range comments {
+		if comment.Missing == "" {
+			unloaded--
+		}
+	}
+	if unloaded < 0 {
+		return 0
+	}
+	return unloaded
+}
+
+// pruneToDisplayPrefix drops the pending kids of level that can not be among the
+// first n comments in display order
+// The loaded comments and pending kids displayed before a kid are a lower bound of
+// its position, deeper levels only insert comments before it.
+func pruneToDisplayPrefix(conversation *model.Conversation, level []pendingKids, n int) {
+	pending := make(map[*model.Conversation]int, len(level))
+	for i, p := range level {
+		pending[p.node] = i
+	}
+
+	// displayed counts the comments displayed before the next one, the root is not displayed
+	displayed := 0
+	var walk func(node *model.Conversation)
+	walk = func(node *model.Conversation) {
+		if i, ok := pending[node]; ok {
+			// pending nodes are the deepest loaded ones, their kids are displayed right after them
+			remaining := n - displayed
+			if remaining < 0 {
+				remaining = 0
+			}
+			if len(level[i].kids) > remaining {
+				level[i].kids = level[i].kids[:remaining]
+			}
+			displayed += len(level[i].kids)
+			return
+		}
+		for _, kid := range node.Kids {
+			displayed++
+			walk(kid)
+		}
+	}
+	walk(conversation)
+}
+
+// conversationPage slices a page of the comments of conversation in display order,
+// depth first with siblings in the order of their parent's kids
+// The returned fragment nests the comments of the page under their ancestors.
+func conversationPage(conversation *model.Conversation, comments []model.Item, page api.Page) ([]model.Item, *model.Conversation, int) {
+	ids := make([]int, 0, len(comments))
+	parents := make(map[int]*model.Conversation)
+	var walk func(node *model.Conversation)
+	walk = func(node *model.Conversation) {
+		for _, kid := range node.Kids {
+			ids = append(ids, kid.ID)
+			parents[kid.ID] = node
+			walk(kid)
+		}
+	}
+	walk(conversation)
+
+	pageIds, next := page.Slice(ids)
+
+	itemsByID := make(map[int]model.Item, len(comments))
+	for _, item := range comments {
+		itemsByID[item.ID] = item
+	}
+
+	fragment := model.NewConversation(conversation.ID)
+	fragments := map[int]*model.Conversation{conversation.ID: fragment}
+	// fragmentOf copies the node of ID and its ancestors into the fragment
+	var fragmentOf func(ID int) *model.Conversation
+	fragmentOf = func(ID int) *model.Conversation {
+		if copied, ok := fragments[ID]; ok {
+			return copied
+		}
+		copied := model.NewConversation(ID)
+		fragments[ID] = copied
+		parent := fragmentOf(parents[ID].ID)
+		parent.Kids = append(parent.Kids, copied)
+		return copied
+	}
+
+	pageComments := make([]model.Item, 0, len(pageIds))
+	for _, ID := range pageIds {
+		pageComments = append(pageComments, itemsByID[ID])
+		fragmentOf(ID)
+	}
+	return pageComments, fragment, next
+}
+
+// getComments fetches itemIds in batches, commentParallelism at a time
+func getComments(ctx context.Context, itemRepo backend.ItemRepo, itemIds []int) (map[int]model.Item, []model.Failure, []error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, commentParallelism)
+
+	items := make(map[int]model.Item, len(itemIds))
+	failures := make([]model.Failure, 0)
+	var errs []error
+
+	for start := 0; start < len(itemIds); start += commentBatchSize {
+		end := start + commentBatchSize
+		if end > len(itemIds) {
+			end = len(itemIds)
+		}
+		batch := itemIds[start:end]
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			batchItems, err := itemRepo.Get(ctx, batch)
+			batchFailures, err := splitFailures(err, false)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, item := range batchItems {
+				items[item.ID] = item
+			}
+			failures = append(failures, batchFailures...)
+			if err != nil {
+				log.Error(ctx, "failed hydrating comment batch of", len(batch), err)
+				errs = append(errs, err)
+				for _, ID := range batch {
+					if _, ok := items[ID]; !ok {
+						failures = append(failures, model.Failure{ID: ID, Reason: model.MissingError, Message: err.Error()})
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return items, failures, errs
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cevaris/hnapi/model"
+)
+
+// stubItemRepo serves items with kids from a map, failing every Get with err when set
+type stubItemRepo struct {
+	items map[int]model.Item
+	err   error
+	calls int32
+}
+
+func (r *stubItemRepo) Get(ctx context.Context, itemIds []int) ([]model.Item, error) {
+	atomic.AddInt32(&r.calls, 1)
+	if r.err != nil {
+		return nil, r.err
+	}
+	items := make([]model.Item, 0, len(itemIds))
+	for _, ID := range itemIds {
+		items = append(items, r.items[ID])
+	}
+	return items, nil
+}
+
+// newWideThread has n top level comments each with a single reply
+func newWideThread(n int) (*stubItemRepo, []int) {
+	repo := &stubItemRepo{items: make(map[int]model.Item)}
+	commentIds := make([]int, 0, n)
+	for i := 1; i <= n; i++ {
+		commentIds = append(commentIds, i)
+		repo.items[i] = model.Item{ID: i, Kids: []int{n + i}}
+		repo.items[n+i] = model.Item{ID: n + i, Parent: i}
+	}
+	return repo, commentIds
+}
+
+func TestHydrateCommentsBatchesLevels(t *testing.T) {
+	ctx := context.Background()
+	itemRepo, commentIds := newWideThread(250)
+
+	comments := make([]model.Item, 0)
+	failures := make([]model.Failure, 0)
+	conversation := model.Conversation{ID: 0}
+	err := hydrateComments(ctx, itemRepo, commentIds, commentLimits{}, &comments, &conversation, &failures)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(comments) != 500 {
+		t.Errorf("expected 500 comments, got %d", len(comments))
+	}
+	// 2 levels of 250 ids, 3 batches each
+	if calls := atomic.LoadInt32(&itemRepo.calls); calls != 6 {
+		t.Errorf("expected 6 batched calls, got %d", calls)
+	}
+	for i, node := range conversation.Kids {
+		if node.ID != i+1 || len(node.Kids) != 1 || node.Kids[0].ID != 251+i {
+			t.Fatalf("expected conversation in kids order, got %d with %v", node.ID, node.Kids)
+		}
+	}
+}
+
+func TestHydrateCommentsAggregatesBatchErrors(t *testing.T) {
+	ctx := context.Background()
+	itemRepo, commentIds := newWideThread(150)
+	itemRepo.err = errors.New("boom")
+
+	comments := make([]model.Item, 0)
+	failures := make([]model.Failure, 0)
+	conversation := model.Conversation{ID: 0}
+	err := hydrateComments(ctx, itemRepo, commentIds, commentLimits{}, &comments, &conversation, &failures)
+
+	commentsErr, ok := err.(*commentsError)
+	if !ok || len(commentsErr.errs) != 2 {
+		t.Errorf("expected both batch errors, got: %v", err)
+	}
+	if len(failures) != 150 || len(comments) != 0 {
+		t.Errorf("expected every comment to fail, got %d failures", len(failures))
+	}
+}
@@ -0,0 +1,434 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/cevaris/hnapi/api"
+	"github.com/cevaris/hnapi/backend"
+	"github.com/cevaris/hnapi/clients"
+	"github.com/cevaris/hnapi/model"
+	"github.com/cevaris/httprouter"
+)
+
+// feedPageSize matches the number of stories on a HN page
+const feedPageSize = 30
+const feedMaxPageSize = 500
+
+func (s *Server) feedItems(feed string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := s.NewContext(r)
+		itemRepo := s.newItemRepo(ctx)
+		feedRepo := s.newFeedRepo(ctx)
+
+		isPrettyJSON, err := api.GetBool(ctx, r, "pretty", false)
+		if err != nil {
+			api.SerializeErr(ctx, w, err)
+			return
+		}
+
+		log.Debug(ctx, "feedItems found pretty param", feed, isPrettyJSON)
+
+		isStrict, err := api.GetBool(ctx, r, "strict", false)
+		if err != nil {
+			api.SerializeErr(ctx, w, err)
+			return
+		}
+
+		page, err := api.GetPage(ctx, r, feedPageSize, feedMaxPageSize)
+		if err != nil {
+			api.SerializeErr(ctx, w, err)
+			return
+		}
+
+		feedItemIds, err := feedRepo.Get(ctx, feed)
+		if err != nil {
+			api.SerializeErr(ctx, w, err)
+			return
+		}
+
+		itemIds, next := page.Slice(feedItemIds)
+		items, err := itemRepo.Get(backend.WithBulk(ctx), itemIds)
+		failures, err := splitFailures(err, isStrict)
+		if err != nil {
+			api.SerializeErr(ctx, w, err)
+			return
+		}
+
+		response := model.Items{
+			Items:    items,
+			Next:     next,
+			Partial:  len(failures) > 0,
+			Failures: failures,
+		}
+
+		api.SerializeData(ctx, w, response, isPrettyJSON)
+	}
+}
+
+const userMaxSubmissions = 100
+
+func (s *Server) user(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := s.NewContext(r)
+	itemRepo := s.newItemRepo(ctx)
+	userRepo := s.newUserRepo(ctx)
+
+	userID := ps.ByName("ID")
+	if len(userID) == 0 {
+		api.SerializeErr(ctx, w, errors.New("missing parameter ':id'"))
+		return
+	}
+
+	isPrettyJSON, err := api.GetBool(ctx, r, "pretty", false)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	isStrict, err := api.GetBool(ctx, r, "strict", false)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	numSubmissions, err := api.GetQueryInt(ctx, r, "submissions", 0)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+	if numSubmissions < 0 || numSubmissions > userMaxSubmissions {
+		api.SerializeErr(ctx, w, fmt.Errorf("invalid 'submissions' param %d, expected a value between 0 and %d", numSubmissions, userMaxSubmissions))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	u, err := userRepo.Get(ctx, userID)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	response := model.Users{
+		Users: []model.User{u},
+	}
+
+	// submitted is ordered most recent first
+	if numSubmissions > 0 && len(u.Submitted) > 0 {
+		submissionIds, _ := api.Page{Limit: numSubmissions}.Slice(u.Submitted)
+		submissions, err := itemRepo.Get(ctx, submissionIds)
+		failures, err := splitFailures(err, isStrict)
+		if err != nil {
+			api.SerializeErr(ctx, w, err)
+			return
+		}
+		response.Submissions = submissions
+		response.Partial = len(failures) > 0
+		response.Failures = failures
+	}
+
+	api.SerializeData(ctx, w, response, isPrettyJSON)
+}
+
+// getItem hydrates a single item, failing with a 404 if it does not exist
+func getItem(ctx context.Context, itemRepo backend.ItemRepo, itemID int) (model.Item, error) {
+	items, err := itemRepo.Get(ctx, []int{itemID})
+	failures, err := splitFailures(err, false)
+	if err != nil {
+		return model.Item{}, err
+	}
+	if len(items) == 0 {
+		return model.Item{}, fmt.Errorf("failed to hydrate %d", itemID)
+	}
+
+	item := items[0]
+	if item.Missing == model.MissingNotFound {
+		return item, &backend.ErrNotFound{Resource: fmt.Sprintf("item %d", itemID), ItemID: itemID, Status: http.StatusNotFound}
+	}
+	if item.Missing != "" {
+		return item, &backend.PartialError{Failures: failures}
+	}
+	return item, nil
+}
+
+func (s *Server) item(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := s.NewContext(r)
+	itemRepo := s.newItemRepo(ctx)
+
+	itemID, err := api.GetInt(ctx, ps, "ID", -1)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+	if itemID == -1 {
+		api.SerializeErr(ctx, w, errors.New("missing parameter ':id'"))
+		return
+	}
+
+	isPrettyJSON, err := api.GetBool(ctx, r, "pretty", false)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+	log.Info(ctx, "just log text")
+	log.Info(ctx, "found pretty param", isPrettyJSON)
+	log.Info(ctx, "one", "two", "three")
+
+	isStrict, err := api.GetBool(ctx, r, "strict", false)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	shape, err := getShape(r)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	order, err := getSort(r)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	limits, err := getCommentLimits(ctx, r)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	item, err := getItem(ctx, itemRepo, itemID)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	comments := make([]model.Item, 0)
+	failures := make([]model.Failure, 0)
+	conversation := model.Conversation{ID: itemID}
+	err = hydrateComments(ctx, itemRepo, item.Kids, limits, &comments, &conversation, &failures)
+	if err != nil {
+		log.Error(ctx, "failed hydrating comments, got", len(comments), "of", len(item.Kids))
+		if isStrict {
+			api.SerializeErr(ctx, w, err)
+			return
+		}
+	}
+	if isStrict && len(failures) > 0 {
+		api.SerializeErr(ctx, w, &backend.PartialError{Failures: failures})
+		return
+	}
+	conversation.UnloadedDescendants = unloadedDescendants(item, comments)
+
+	// comments are ordered by time unless a sort is requested,
+	// which orders both them and each level of the conversation
+	if order == "" {
+		comments = sortItemsByTime(comments)
+	} else {
+		comments = sortConversation(&conversation, comments, order)
+	}
+
+	response := model.Items{
+		Items:        []model.Item{item},
+		Conversation: conversation,
+		Comments:     comments,
+		Partial:      err != nil || len(failures) > 0,
+		Failures:     failures,
+	}
+	if shape == shapeTree {
+		embedComments(&response.Conversation, append(response.Comments, item))
+		response.Comments = nil
+	}
+
+	api.SerializeData(ctx, w, response, isPrettyJSON)
+}
+
+// commentsPageSize comments per page of a subtree
+const commentsPageSize = 50
+const commentsMaxPageSize = 500
+
+func (s *Server) itemComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := s.NewContext(r)
+	itemRepo := s.newItemRepo(ctx)
+
+	itemID, err := api.GetInt(ctx, ps, "ID", -1)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+	if itemID == -1 {
+		api.SerializeErr(ctx, w, errors.New("missing parameter ':id'"))
+		return
+	}
+
+	isPrettyJSON, err := api.GetBool(ctx, r, "pretty", false)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	isStrict, err := api.GetBool(ctx, r, "strict", false)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	shape, err := getShape(r)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	page, err := api.GetCursorPage(ctx, r, commentsPageSize, commentsMaxPageSize)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	item, err := getItem(ctx, itemRepo, itemID)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	// hydrate up to the page, and one more comment to tell whether there is a next page,
+	// earlier pages of the subtree are served from the cache
+	comments := make([]model.Item, 0)
+	failures := make([]model.Failure, 0)
+	conversation := model.Conversation{ID: itemID}
+	limits := commentLimits{displayPrefix: page.Offset + page.Limit + 1}
+	err = hydrateComments(ctx, itemRepo, item.Kids, limits, &comments, &conversation, &failures)
+	if err != nil {
+		log.Error(ctx, "failed hydrating comments of", itemID, err)
+		if isStrict {
+			api.SerializeErr(ctx, w, err)
+			return
+		}
+	}
+	if isStrict && len(failures) > 0 {
+		api.SerializeErr(ctx, w, &backend.PartialError{Failures: failures})
+		return
+	}
+
+	pageComments, fragment, next := conversationPage(&conversation, comments, page)
+
+	response := model.Items{
+		Items:        []model.Item{item},
+		Conversation: *fragment,
+		Comments:     pageComments,
+		Next:         next,
+		Partial:      err != nil || len(failures) > 0,
+		Failures:     failures,
+	}
+	if shape == shapeTree {
+		embedComments(&response.Conversation, append(response.Comments, item))
+		response.Comments = nil
+	}
+
+	api.SerializeData(ctx, w, response, isPrettyJSON)
+}
+
+func (s *Server) items(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := s.NewContext(r)
+	itemRepo := s.newItemRepo(ctx)
+
+	itemIds, err := api.GetSlice(ctx, r, "ids", []int{})
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	if len(itemIds) == 0 {
+		api.SerializeErr(ctx, w, errors.New("missing 'ids' parameter or values"))
+		return
+	}
+
+	isPrettyJSON, err := api.GetBool(ctx, r, "pretty", false)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+	log.Debug(ctx, "found pretty param", isPrettyJSON)
+
+	isStrict, err := api.GetBool(ctx, r, "strict", false)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	items, err := itemRepo.Get(ctx, itemIds)
+	failures, err := splitFailures(err, isStrict)
+	if err != nil {
+		api.SerializeErr(ctx, w, err)
+		return
+	}
+
+	response := model.Items{
+		Items:    items,
+		Partial:  len(failures) > 0,
+		Failures: failures,
+	}
+
+	api.SerializeData(ctx, w, response, isPrettyJSON)
+}
+
+func (s *Server) breakerStats(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := s.NewContext(r)
+	api.SerializeData(ctx, w, s.breakers.Stats(), true)
+}
+
+func (s *Server) retryStats(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := s.NewContext(r)
+	api.SerializeData(ctx, w, s.retries.Stats(), true)
+}
+
+// errNoCacheStats only the tiered cache counts hits and misses
+type errNoCacheStats struct{}
+
+func (errNoCacheStats) Error() string {
+	return "cache stats require the tiered cache"
+}
+
+// HTTPStatus maps to 404
+func (errNoCacheStats) HTTPStatus() int {
+	return http.StatusNotFound
+}
+
+func (s *Server) cacheStats(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := s.NewContext(r)
+	tiered, ok := s.NewCacheClient(ctx).(*clients.TieredCacheClient)
+	if !ok {
+		api.SerializeErr(ctx, w, errNoCacheStats{})
+		return
+	}
+	api.SerializeData(ctx, w, tiered.Stats(), true)
+}
+
+// splitFailures separates the failures of a partial error from other errors
+// In strict mode partial errors are returned as errors as well
+func splitFailures(err error, isStrict bool) ([]model.Failure, error) {
+	if partialErr, ok := err.(*backend.PartialError); ok && !isStrict {
+		return partialErr.Failures, nil
+	}
+	return nil, err
+}
+
+func sortItemsByTime(source []model.Item) []model.Item {
+	sort.Slice(source, func(i, j int) bool { return source[i].Time < source[j].Time })
+	return source
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/cevaris/hnapi/backend"
+	"github.com/cevaris/hnapi/clients"
+	"github.com/cevaris/httprouter"
+	"github.com/cevaris/timber"
+)
+
+var log = timber.NewGoogleLogger()
+
+// Server wires the http handlers to their backends
+type Server struct {
+	// NewContext seeds the context of each request
+	NewContext func(*http.Request) context.Context
+	// HTTPClient reaches the HN API, shared by every request
+	HTTPClient clients.HTTPClient
+	// BaseURLs of the HN API and its mirrors in fallback order, defaults to backend.DefaultBaseURL
+	BaseURLs []string
+	// NewCacheClient constructs the client used to cache HN API responses
+	NewCacheClient func(context.Context) clients.CacheClient
+	// MaxRequests bounds concurrent requests to the HN API, defaults to backend.DefaultMaxRequests
+	MaxRequests int
+	// MaxBulkRequests bounds the share of MaxRequests feeds may use, defaults to backend.DefaultMaxBulkRequests
+	MaxBulkRequests int
+	// RetryPolicy of requests to the HN API, defaults to clients.DefaultRetryPolicy
+	RetryPolicy clients.RetryPolicy
+	// BreakerConfig of the circuit breaker around the HN API, defaults to clients.DefaultBreakerConfig
+	BreakerConfig clients.BreakerConfig
+	// ItemCacheTTLs overrides the per item type cache ttls, defaults to backend.DefaultItemCacheTTLs
+	ItemCacheTTLs backend.ItemCacheTTLs
+
+	// itemCalls coalesces item hydrations across concurrent requests
+	itemCalls *backend.ItemCallGroup
+	// limiter bounds item hydrations across concurrent requests
+	limiter backend.Limiter
+	// retries counts retried requests to the HN API
+	retries *clients.RetryCounter
+	// breakers fail requests to the HN API fast while it is unhealthy
+	breakers *clients.CircuitBreakers
+	// httpClient wraps HTTPClient with the breaker and retries
+	httpClient clients.HTTPClient
+}
+
+// Router registers all routes
+func (s *Server) Router() *httprouter.Router {
+	s.itemCalls = backend.NewItemCallGroup()
+	s.retries = &clients.RetryCounter{}
+	if s.RetryPolicy.MaxAttempts == 0 {
+		s.RetryPolicy = clients.DefaultRetryPolicy
+	}
+	if s.BreakerConfig.FailureThreshold == 0 {
+		s.BreakerConfig = clients.DefaultBreakerConfig
+	}
+	s.breakers = clients.NewCircuitBreakers(s.BreakerConfig)
+	s.httpClient = clients.NewRetryingHTTPClient(clients.NewBreakerHTTPClient(s.HTTPClient, s.breakers), s.RetryPolicy, s.retries)
+	s.limiter = backend.NewLimiter(orDefault(s.MaxRequests, backend.DefaultMaxRequests), orDefault(s.MaxBulkRequests, backend.DefaultMaxBulkRequests))
+
+	router := httprouter.New()
+	for feed := range backend.Feeds {
+		router.GET("/feed/"+feed, s.feedItems(feed))
+	}
+	router.GET("/items/:ID", s.item)
+	router.GET("/items/:ID/comments", s.itemComments)
+	router.GET("/items", s.items)
+	router.GET("/users/:ID", s.user)
+	router.GET("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) { pprof.Index(w, r) })
+	router.GET("/debug/breaker", s.breakerStats)
+	router.GET("/debug/retries", s.retryStats)
+	router.GET("/debug/cache", s.cacheStats)
+	return router
+}
+
+func (s *Server) newItemRepo(ctx context.Context) backend.ItemRepo {
+	itemBackend := backend.NewCoalescingItemBackend(backend.NewFireBaseItemBackend(s.httpClient, s.limiter, s.BaseURLs...), s.itemCalls)
+	cacheBackend := s.NewCacheClient(ctx)
+	ttls := s.ItemCacheTTLs
+	if ttls == nil {
+		ttls = backend.DefaultItemCacheTTLs
+	}
+	itemRepo := backend.NewCachedItemRepoWithTTLs(itemBackend, cacheBackend, ttls)
+	return itemRepo
+}
+
+func (s *Server) newFeedRepo(ctx context.Context) backend.FeedRepo {
+	feedBackend := backend.NewFireBaseFeedBackend(s.httpClient, s.BaseURLs...)
+	cacheBackend := s.NewCacheClient(ctx)
+	feedRepo := backend.NewCachedFeedRepo(feedBackend, cacheBackend)
+	return feedRepo
+}
+
+func (s *Server) newUserRepo(ctx context.Context) backend.UserRepo {
+	userBackend := backend.NewFireBaseUserBackend(s.httpClient, s.BaseURLs...)
+	cacheBackend := s.NewCacheClient(ctx)
+	userRepo := backend.NewCachedUserRepo(userBackend, cacheBackend)
+	return userRepo
+}
+
+func orDefault(value int, defaultValue int) int {
+	if value > 0 {
+		return value
+	}
+	return defaultValue
+}
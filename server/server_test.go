@@ -0,0 +1,561 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cevaris/hnapi/api"
+	"github.com/cevaris/hnapi/backend"
+	"github.com/cevaris/hnapi/clients"
+	"github.com/cevaris/hnapi/hntest"
+	"github.com/cevaris/hnapi/model"
+)
+
+func newTestServer(fake *hntest.Server) *Server {
+	cacheClient := clients.NewLRUCacheClient(100, clients.NewJSONCodec())
+	return &Server{
+		NewContext:     func(r *http.Request) context.Context { return r.Context() },
+		HTTPClient:     clients.NewGoPClient(),
+		BaseURLs:       []string{fake.BaseURL()},
+		NewCacheClient: func(ctx context.Context) clients.CacheClient { return cacheClient },
+		RetryPolicy:    clients.RetryPolicy{MaxAttempts: 1},
+	}
+}
+
+type testResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// get serves path, decoding the data of ok responses into result
+func get(t *testing.T, s *Server, path string, result interface{}) int {
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+	var response testResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode %s response %q: %v", path, w.Body.String(), err)
+	}
+	if w.Code == http.StatusOK && result != nil {
+		if err := json.Unmarshal(response.Data, result); err != nil {
+			t.Fatalf("failed to decode %s data: %v", path, err)
+		}
+	}
+	return w.Code
+}
+
+func itemIds(items []model.Item) []int {
+	result := make([]int, 0, len(items))
+	for _, item := range items {
+		result = append(result, item.ID)
+	}
+	return result
+}
+
+func equalIds(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFeedItems(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+
+	var response model.Items
+	if status := get(t, s, "/feed/top?limit=2", &response); status != http.StatusOK {
+		t.Fatalf("expected ok, got %d", status)
+	}
+	if !equalIds(itemIds(response.Items), []int{1, 8}) || response.Next != 2 {
+		t.Errorf("expected first page of top feed, got: %v next %d", itemIds(response.Items), response.Next)
+	}
+
+	response = model.Items{}
+	get(t, s, "/feed/top?offset=2&limit=2", &response)
+	if !equalIds(itemIds(response.Items), []int{7}) || response.Next != 0 {
+		t.Errorf("expected last page of top feed, got: %v next %d", itemIds(response.Items), response.Next)
+	}
+}
+
+func TestItems(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+
+	var response model.Items
+	if status := get(t, s, "/items?ids=8,1,99", &response); status != http.StatusOK {
+		t.Fatalf("expected ok, got %d", status)
+	}
+	if !equalIds(itemIds(response.Items), []int{8, 1, 99}) {
+		t.Errorf("expected items in request order, got: %v", itemIds(response.Items))
+	}
+	if !response.Partial || len(response.Failures) != 1 || response.Failures[0].ID != 99 {
+		t.Errorf("expected item 99 to fail, got: %v", response.Failures)
+	}
+
+	if status := get(t, s, "/items?ids=8,99&strict=true", nil); status == http.StatusOK {
+		t.Errorf("expected strict request with missing items to fail")
+	}
+}
+
+func TestItem(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+
+	var response model.Items
+	if status := get(t, s, "/items/1", &response); status != http.StatusOK {
+		t.Fatalf("expected ok, got %d", status)
+	}
+	if !equalIds(itemIds(response.Items), []int{1}) {
+		t.Errorf("expected root item, got: %v", itemIds(response.Items))
+	}
+	if !equalIds(itemIds(response.Comments), []int{3, 2, 5, 4, 6}) {
+		t.Errorf("expected comments ordered by time, got: %v", itemIds(response.Comments))
+	}
+
+	conversation := response.Conversation
+	if len(conversation.Kids) != 2 || conversation.Kids[0].ID != 2 || conversation.Kids[1].ID != 3 {
+		t.Fatalf("expected conversation ordered by kids, got: %v", conversation.Kids)
+	}
+	replies := conversation.Kids[0].Kids
+	if len(replies) != 2 || replies[0].ID != 4 || len(replies[0].Kids) != 1 || replies[0].Kids[0].ID != 6 {
+		t.Errorf("expected nested replies, got: %v", replies)
+	}
+	if response.Partial {
+		t.Errorf("expected complete response, got failures: %v", response.Failures)
+	}
+}
+
+func TestItemWithCommentLimits(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+
+	var response model.Items
+	if status := get(t, s, "/items/1?depth=1", &response); status != http.StatusOK {
+		t.Fatalf("expected ok, got %d", status)
+	}
+	if !equalIds(itemIds(response.Comments), []int{3, 2}) {
+		t.Errorf("expected top level comments, got: %v", itemIds(response.Comments))
+	}
+	if node := response.Conversation.Kids[0]; !equalIds(node.UnloadedIds, []int{4, 5}) {
+		t.Errorf("expected replies of comment 2 to be unloaded, got: %v", node)
+	}
+	if response.Conversation.UnloadedDescendants != 3 {
+		t.Errorf("expected comments 4, 5 and 6 to be unloaded, got: %d", response.Conversation.UnloadedDescendants)
+	}
+
+	response = model.Items{}
+	get(t, s, "/items/1?kidsLimit=1", &response)
+	if !equalIds(itemIds(response.Comments), []int{2, 4, 6}) {
+		t.Errorf("expected first kid of each comment, got: %v", itemIds(response.Comments))
+	}
+	if response.Conversation.UnloadedDescendants != 2 || !equalIds(response.Conversation.UnloadedIds, []int{3}) {
+		t.Errorf("expected comments 3 and 5 to be unloaded, got: %v", response.Conversation)
+	}
+
+	response = model.Items{}
+	get(t, s, "/items/1?maxComments=3", &response)
+	if len(response.Comments) != 3 {
+		t.Errorf("expected 3 comments, got: %v", itemIds(response.Comments))
+	}
+	if node := response.Conversation.Kids[0]; !equalIds(node.UnloadedIds, []int{5}) {
+		t.Errorf("expected comment 5 to be unloaded, got: %v", node)
+	}
+
+	if status := get(t, s, "/items/1?depth=-1", nil); status != http.StatusBadRequest {
+		t.Errorf("expected invalid depth to be rejected, got %d", status)
+	}
+}
+
+func TestItemComments(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+
+	var response model.Items
+	if status := get(t, s, "/items/2/comments?limit=2", &response); status != http.StatusOK {
+		t.Fatalf("expected ok, got %d", status)
+	}
+	if !equalIds(itemIds(response.Comments), []int{4, 6}) || response.Next != 2 {
+		t.Errorf("expected first page in display order, got: %v next %d", itemIds(response.Comments), response.Next)
+	}
+	kids := response.Conversation.Kids
+	if response.Conversation.ID != 2 || len(kids) != 1 || kids[0].ID != 4 || len(kids[0].Kids) != 1 || kids[0].Kids[0].ID != 6 {
+		t.Errorf("expected nested fragment, got: %v", response.Conversation)
+	}
+
+	requests := fake.Requests(hntest.ItemPath(4))
+	response = model.Items{}
+	get(t, s, "/items/2/comments?limit=2&cursor=2", &response)
+	if !equalIds(itemIds(response.Comments), []int{5}) || response.Next != 0 {
+		t.Errorf("expected last page, got: %v next %d", itemIds(response.Comments), response.Next)
+	}
+	if len(response.Conversation.Kids) != 1 || response.Conversation.Kids[0].ID != 5 {
+		t.Errorf("expected fragment of the last page, got: %v", response.Conversation)
+	}
+	if fake.Requests(hntest.ItemPath(4)) != requests {
+		t.Errorf("expected earlier pages to be served from cache")
+	}
+}
+
+func TestItemCommentsHydratesOnlyThePage(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+
+	var response model.Items
+	if status := get(t, s, "/items/1/comments?limit=1", &response); status != http.StatusOK {
+		t.Fatalf("expected ok, got %d", status)
+	}
+	if !equalIds(itemIds(response.Comments), []int{2}) || response.Next != 1 {
+		t.Errorf("expected first comment, got: %v next %d", itemIds(response.Comments), response.Next)
+	}
+	for _, itemID := range []int{5, 6} {
+		if requests := fake.Requests(hntest.ItemPath(itemID)); requests != 0 {
+			t.Errorf("expected item %d past the page not to be fetched, got %d requests", itemID, requests)
+		}
+	}
+
+	response = model.Items{}
+	get(t, s, "/items/1/comments?cursor=3&limit=2", &response)
+	if !equalIds(itemIds(response.Comments), []int{5, 3}) || response.Next != 0 {
+		t.Errorf("expected last page in display order, got: %v next %d", itemIds(response.Comments), response.Next)
+	}
+}
+
+// countingItemRepo counts the Get calls of an ItemRepo
+type countingItemRepo struct {
+	backend.ItemRepo
+	mu    sync.Mutex
+	calls int
+}
+
+func (r *countingItemRepo) Get(ctx context.Context, itemIds []int) ([]model.Item, error) {
+	r.mu.Lock()
+	r.calls++
+	r.mu.Unlock()
+	return r.ItemRepo.Get(ctx, itemIds)
+}
+
+func TestHydrateCommentsDisplayPrefixFetchesEachLevelOnce(t *testing.T) {
+	ctx := context.Background()
+	// a story with 60 comments of one reply each
+	fixtures := hntest.Fixtures{Items: map[int]model.Item{}}
+	story := model.Item{ID: 1, Type: "story"}
+	for i := 0; i < 60; i++ {
+		commentID, replyID := 100+i, 200+i
+		story.Kids = append(story.Kids, commentID)
+		fixtures.Items[commentID] = model.Item{ID: commentID, Type: "comment", Parent: 1, Kids: []int{replyID}}
+		fixtures.Items[replyID] = model.Item{ID: replyID, Type: "comment", Parent: commentID}
+	}
+	fixtures.Items[1] = story
+	fake := hntest.NewServer(fixtures)
+	defer fake.Close()
+	s := newTestServer(fake)
+	s.Router()
+
+	itemRepo := &countingItemRepo{ItemRepo: s.newItemRepo(ctx)}
+	comments := make([]model.Item, 0)
+	failures := make([]model.Failure, 0)
+	conversation := model.Conversation{ID: 1}
+	err := hydrateComments(ctx, itemRepo, story.Kids, commentLimits{displayPrefix: 51}, &comments, &conversation, &failures)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if itemRepo.calls != 2 {
+		t.Errorf("expected one Get per level, got %d", itemRepo.calls)
+	}
+
+	pageComments, _, next := conversationPage(&conversation, comments, api.Page{Limit: 50})
+	if len(pageComments) != 50 || pageComments[0].ID != 100 || pageComments[1].ID != 200 || pageComments[49].ID != 224 || next != 50 {
+		t.Errorf("expected the first 50 comments in display order, got: %v next %d", itemIds(pageComments), next)
+	}
+	if requests := fake.Requests(hntest.ItemPath(159)); requests != 0 {
+		t.Errorf("expected comments past the page not to be fetched, got %d requests", requests)
+	}
+}
+
+func TestItemTreeShape(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+
+	var response model.Items
+	if status := get(t, s, "/items/1?shape=tree", &response); status != http.StatusOK {
+		t.Fatalf("expected ok, got %d", status)
+	}
+	if response.Comments != nil {
+		t.Errorf("expected no flat comments, got: %v", itemIds(response.Comments))
+	}
+
+	root := response.Conversation
+	if root.Item == nil || root.Item.ID != 1 || root.Depth != 0 || root.Replies != 5 {
+		t.Fatalf("expected root with 5 replies, got: %v", root)
+	}
+	reply := root.Kids[0].Kids[0]
+	if reply.Item == nil || reply.Item.Text != "reply" || reply.Depth != 2 || reply.Replies != 1 {
+		t.Errorf("expected embedded reply at depth 2, got: %v", reply)
+	}
+
+	if status := get(t, s, "/items/1?shape=graph", nil); status != http.StatusBadRequest {
+		t.Errorf("expected invalid shape to be rejected, got %d", status)
+	}
+}
+
+func TestItemSort(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+
+	tests := []struct {
+		order    string
+		comments []int
+		kids     []int
+	}{
+		{"hn", []int{2, 4, 6, 5, 3}, []int{2, 3}},
+		{"oldest", []int{3, 2, 5, 4, 6}, []int{3, 2}},
+		{"newest", []int{6, 4, 5, 2, 3}, []int{2, 3}},
+		{"most-replies", []int{2, 4, 6, 5, 3}, []int{2, 3}},
+		{"author", []int{5, 4, 3, 2, 6}, []int{3, 2}},
+	}
+	for _, test := range tests {
+		var response model.Items
+		if status := get(t, s, "/items/1?sort="+test.order, &response); status != http.StatusOK {
+			t.Fatalf("expected ok for sort %s, got %d", test.order, status)
+		}
+		if !equalIds(itemIds(response.Comments), test.comments) {
+			t.Errorf("expected %s comments %v, got: %v", test.order, test.comments, itemIds(response.Comments))
+		}
+		kids := make([]int, 0)
+		for _, kid := range response.Conversation.Kids {
+			kids = append(kids, kid.ID)
+		}
+		if !equalIds(kids, test.kids) {
+			t.Errorf("expected %s conversation %v, got: %v", test.order, test.kids, kids)
+		}
+	}
+
+	if status := get(t, s, "/items/1?sort=best", nil); status != http.StatusBadRequest {
+		t.Errorf("expected invalid sort to be rejected, got %d", status)
+	}
+}
+
+func TestItemNotFound(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+
+	if status := get(t, s, "/items/99", nil); status != http.StatusNotFound {
+		t.Errorf("expected not found, got %d", status)
+	}
+}
+
+func TestItemWithFailedComments(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	fake.SetStatus(hntest.ItemPath(4), http.StatusInternalServerError)
+	s := newTestServer(fake)
+
+	var response model.Items
+	if status := get(t, s, "/items/1", &response); status != http.StatusOK {
+		t.Fatalf("expected ok, got %d", status)
+	}
+	if !response.Partial || len(response.Failures) != 1 || response.Failures[0].ID != 4 {
+		t.Errorf("expected comment 4 to fail, got: %v", response.Failures)
+	}
+	for _, comment := range response.Comments {
+		if comment.ID == 6 {
+			t.Errorf("expected replies of failed comment to be skipped")
+		}
+	}
+
+	if status := get(t, s, "/items/1?strict=true", nil); status == http.StatusOK {
+		t.Errorf("expected strict request with failed comments to fail")
+	}
+}
+
+func TestUser(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+
+	var response model.Users
+	if status := get(t, s, "/users/pg?submissions=2", &response); status != http.StatusOK {
+		t.Fatalf("expected ok, got %d", status)
+	}
+	if len(response.Users) != 1 || response.Users[0].Karma != 157236 {
+		t.Errorf("expected user pg, got: %v", response.Users)
+	}
+	if !equalIds(itemIds(response.Submissions), []int{8, 3}) {
+		t.Errorf("expected most recent submissions, got: %v", itemIds(response.Submissions))
+	}
+
+	if status := get(t, s, "/users/nobody", nil); status != http.StatusNotFound {
+		t.Errorf("expected unknown user to be not found, got %d", status)
+	}
+}
+
+func TestUpstreamDown(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	fake.Close()
+	s := newTestServer(fake)
+	s.BreakerConfig = clients.BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1}
+	router := s.Router()
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/users/pg", http.StatusBadGateway},
+		{"/feed/top", http.StatusBadGateway},
+		{"/feed/new", http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.status {
+			t.Errorf("expected %s to respond %d while upstream is down, got %d: %s", test.path, test.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestBreakerStats(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+
+	if status := get(t, s, "/debug/breaker", nil); status != http.StatusOK {
+		t.Errorf("expected ok, got %d", status)
+	}
+}
+
+func TestRetryStats(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	fake.SetStatus(hntest.UserPath("pg"), http.StatusServiceUnavailable)
+	s := newTestServer(fake)
+	s.RetryPolicy = clients.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	router := s.Router()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/users/pg", nil))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/debug/retries", nil))
+	var response struct {
+		Data clients.RetryStats `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode retry stats %q: %v", w.Body.String(), err)
+	}
+	if response.Data.Attempts != 2 || response.Data.Retries != 1 || response.Data.Exhausted != 1 {
+		t.Errorf("expected the failed user request to be retried once, got: %+v", response.Data)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	s := newTestServer(fake)
+	if status := get(t, s, "/debug/cache", nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 without a tiered cache, got %d", status)
+	}
+
+	cacheClient := clients.NewTieredCacheClient(clients.NewLRUCacheClient(100, clients.NewJSONCodec()), 100, time.Minute, 0)
+	s.NewCacheClient = func(ctx context.Context) clients.CacheClient { return cacheClient }
+	get(t, s, "/users/pg", nil)
+	get(t, s, "/users/pg", nil)
+
+	var stats clients.TieredCacheStats
+	if status := get(t, s, "/debug/cache", &stats); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	expected := clients.TieredCacheStats{LocalHits: 1, LocalMisses: 1, RemoteMisses: 1}
+	if stats != expected {
+		t.Errorf("expected one miss then one local hit, got: %+v", stats)
+	}
+}
+
+func TestHydrateComments(t *testing.T) {
+	ctx := context.Background()
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	fake.SetNull(hntest.ItemPath(3))
+	s := newTestServer(fake)
+	s.Router()
+
+	comments := make([]model.Item, 0)
+	failures := make([]model.Failure, 0)
+	conversation := model.Conversation{ID: 1}
+	err := hydrateComments(ctx, s.newItemRepo(ctx), []int{2, 3}, commentLimits{}, &comments, &conversation, &failures)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(failures) != 1 || failures[0].ID != 3 || failures[0].Reason != model.MissingNotFound {
+		t.Errorf("expected comment 3 to be not found, got: %v", failures)
+	}
+	if len(conversation.Kids) != 2 {
+		t.Errorf("expected both comments in conversation, got: %v", conversation.Kids)
+	}
+}
+
+func TestHydrateCommentsTimesOut(t *testing.T) {
+	fake := hntest.NewThreadServer(t)
+	defer fake.Close()
+	fake.SetLatency(time.Second)
+	s := newTestServer(fake)
+	s.Router()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	comments := make([]model.Item, 0)
+	failures := make([]model.Failure, 0)
+	conversation := model.Conversation{ID: 1}
+	hydrateComments(ctx, s.newItemRepo(ctx), []int{2, 3}, commentLimits{}, &comments, &conversation, &failures)
+	if len(failures) != 2 {
+		t.Errorf("expected slow comments to fail, got: %v", failures)
+	}
+}
+
+func TestItemReplaysRecordedThread(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recordings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fake := hntest.NewThreadServer(t)
+	s := newTestServer(fake)
+	s.HTTPClient = clients.NewRecordingHTTPClient(clients.NewGoPClient(), dir)
+	var recorded model.Items
+	get(t, s, "/items/1", &recorded)
+	fake.Close()
+
+	s = newTestServer(fake)
+	s.HTTPClient = clients.NewReplayHTTPClient(dir)
+	var replayed model.Items
+	if status := get(t, s, "/items/1", &replayed); status != http.StatusOK {
+		t.Fatalf("expected ok, got %d", status)
+	}
+	if !equalIds(itemIds(replayed.Comments), itemIds(recorded.Comments)) || replayed.Partial {
+		t.Errorf("expected recorded thread %v, got: %v", itemIds(recorded.Comments), itemIds(replayed.Comments))
+	}
+}